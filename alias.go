@@ -0,0 +1,136 @@
+package commander
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Alias gives other names for the command, which may be given on the command
+// line in place of the literals its definition starts with. The words of an
+// alias replace the last words of the name of the command, so for a command
+// mapped in a group the alias is given for its name within the group. For
+// example, mapping "remove id=(int)" in the "user" group with:
+//
+//	Alias("rm")
+//
+// lets it be run as "user rm 1" as well as "user remove 1". Each word of an
+// alias is another name for the word it replaces. Aliases are shown in the
+// help for the command.
+//
+// Map returns an error if an alias has more words than the name of the
+// command.
+func Alias(aliases ...string) MapOption {
+	return func(cmd *command) error {
+		name := cmd.nameArguments()
+		for _, alias := range aliases {
+			words := strings.Fields(alias)
+			switch {
+			case len(words) == 0:
+				return errors.New("an alias may not be empty")
+			case len(name) == 0:
+				return fmt.Errorf("the alias %s was given for a command that does not start with a literal", alias)
+			case len(words) > len(name):
+				return fmt.Errorf("the alias %s has more words than the name %s", alias, cmd.name())
+			}
+			for i, word := range words {
+				a := name[len(name)-len(words)+i]
+				if !containsString(a.names(), word) {
+					a.aliases = append(a.aliases, word)
+				}
+			}
+			cmd.aliases = append(cmd.aliases, strings.Join(words, delimiterArgumentSeparator))
+		}
+		return nil
+	}
+}
+
+// SetPrefixMatching sets whether the literals of commands may be given as any
+// prefix of them or their aliases, such as "proj cre" for "project create".
+// A prefix is the least specific way to match an argument, so a literal given
+// in full, or a capture that represents the argument, is chosen over a literal
+// it is a prefix of. If a prefix could stand for the literals of more than
+// one command, Run returns a *MismatchError listing them. The default is
+// false.
+func (c *Commander) SetPrefixMatching(prefixMatching bool) {
+	c.prefixMatching = prefixMatching
+}
+
+// ambiguousPrefix gets a *MismatchError listing the literals a prefix in the
+// arguments could stand for, if it is a prefix of a literal of the chosen
+// command and of a different literal of another command that represents the
+// arguments, or nil if there is no such prefix
+func (c *Commander) ambiguousPrefix(chosen *command, args []string) *MismatchError {
+
+	if !c.prefixMatching {
+		return nil
+	}
+
+	parsed, _ := chosen.parseOptions(args)
+	matched, _ := chosen.match(parsed.positional)
+
+	for i, argIndex := range matched {
+		cmdArg := parsed.positional[i]
+		if !chosen.arguments[argIndex].isAbbreviatedBy(cmdArg) {
+			continue
+		}
+
+		candidates := []string{chosen.arguments[argIndex].literal}
+		for _, cmd := range c.commands {
+			if cmd == chosen || cmd.hidden || !cmd.represents(args) {
+				continue
+			}
+			if a := cmd.argumentAt(args, parsed.positions[i]); a != nil && a.isLiteral() && !containsString(candidates, a.literal) {
+				candidates = append(candidates, a.literal)
+			}
+		}
+
+		if len(candidates) > 1 {
+			sort.Strings(candidates)
+			return &MismatchError{Kind: MismatchAmbiguous, Position: parsed.positions[i] + 1, Arg: cmdArg, Candidates: candidates}
+		}
+	}
+
+	return nil
+
+}
+
+// argumentAt gets the argument of this command the argument at position in
+// rawArgs is matched to, or nil if it is not matched to one
+func (c *command) argumentAt(rawArgs []string, position int) *argument {
+
+	parsed, _ := c.parseOptions(rawArgs)
+	matched, _ := c.match(parsed.positional)
+	for i, argIndex := range matched {
+		if parsed.positions[i] == position {
+			return c.arguments[argIndex]
+		}
+	}
+	return nil
+
+}
+
+// title gets the definition of the command followed by its aliases, if it has
+// any, such as "remove id=(int) (alias rm)"
+func (c *command) title() string {
+
+	switch len(c.aliases) {
+	case 0:
+		return c.definition
+	case 1:
+		return fmt.Sprintf("%s (alias %s)", c.definition, c.aliases[0])
+	}
+	return fmt.Sprintf("%s (aliases %s)", c.definition, strings.Join(c.aliases, ", "))
+
+}
+
+// joinAlternatives joins the items into a list such as "a, b or c"
+func joinAlternatives(items []string) string {
+
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " or " + items[len(items)-1]
+
+}
@@ -0,0 +1,126 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAlias_Alias(t *testing.T) {
+
+	c := New()
+	stdout := new(bytes.Buffer)
+	c.SetStdout(stdout)
+
+	var removed interface{}
+	assert.NoError(t, c.Map("remove id=(int)", "Removes", "", func(args objx.Map) {
+		removed = args["id"]
+	}, Alias("rm", "del")))
+
+	var created interface{}
+	assert.NoError(t, c.Group("user", "").Map("create name=(string)", "Creates", "", func(args objx.Map) {
+		created = args["name"]
+	}, Alias("new")))
+
+	assert.NoError(t, c.Run([]string{"rm", "1"}))
+	assert.Equal(t, removed, int64(1))
+	assert.NoError(t, c.Run([]string{"del", "2"}))
+	assert.Equal(t, removed, int64(2))
+	assert.NoError(t, c.Run([]string{"remove", "3"}))
+	assert.Equal(t, removed, int64(3))
+
+	assert.NoError(t, c.Run([]string{"user", "new", "mat"}))
+	assert.Equal(t, created, "mat")
+	assert.Error(t, c.Run([]string{"new", "mat"}))
+
+	assert.NoError(t, c.Run([]string{"help"}))
+	assert.Contains(t, stdout.String(), "    remove id=(int) (aliases rm, del) - Removes\n")
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help", "user", "new"}))
+	assert.Contains(t, stdout.String(), "    user create name=(string) (alias new) - Creates\n")
+
+}
+
+func TestAlias_Errors(t *testing.T) {
+
+	c := New()
+	assert.NoError(t, c.Map("rm", "", "", HandlerFunc))
+
+	err := c.Map("remove id=(int)", "", "", HandlerFunc, Alias("rm id"))
+	assert.Equal(t, err.Error(), `definition "remove id=(int)": the alias rm id has more words than the name remove`)
+
+	err = c.Map("[n=(int)]", "", "", HandlerFunc, Alias("n"))
+	assert.Equal(t, err.Error(), `definition "[n=(int)]": the alias n was given for a command that does not start with a literal`)
+
+	err = c.Map("remove", "", "", HandlerFunc, Alias(" "))
+	assert.Equal(t, err.Error(), `definition "remove": an alias may not be empty`)
+
+	// an alias that is the name of another command is ambiguous
+	err = c.Map("remove", "", "", HandlerFunc, Alias("rm"))
+	assert.Equal(t, err.Error(), `definition "remove": it is ambiguous with (rm), as they can represent the same arguments just as specifically`)
+
+}
+
+func TestAlias_PrefixMatching(t *testing.T) {
+
+	c := New()
+	c.SetStderr(new(bytes.Buffer))
+
+	var ran string
+	handler := func(name string) Handler {
+		return func(objx.Map) {
+			ran = name
+		}
+	}
+	c.Map("project create name=(string)", "", "", handler("create"))
+	c.Map("project copy src=(string)", "", "", handler("copy"))
+	c.Map("profile show", "", "", handler("profile"))
+	c.Map("show all", "", "", handler("all"))
+	c.Map("show allow", "", "", handler("allow"))
+	c.Map("find all", "", "", handler("find all"))
+	c.Map("find name=(string)", "", "", handler("find name"))
+	c.Map("remove id=(int)", "", "", handler("remove"), Alias("delete"))
+
+	// prefixes are not matched unless prefix matching is on
+	assert.Error(t, c.Run([]string{"proj", "cre", "x"}))
+
+	c.SetPrefixMatching(true)
+
+	tests := []struct {
+		args []string
+		ran  string
+	}{
+		{[]string{"proj", "cre", "x"}, "create"},
+		{[]string{"project", "cop", "x"}, "copy"},
+		{[]string{"pro", "show"}, "profile"},
+		{[]string{"show", "all"}, "all"},
+		{[]string{"show", "allo"}, "allow"},
+		{[]string{"find", "all"}, "find all"},
+		{[]string{"fi", "a"}, "find name"},
+		{[]string{"del", "1"}, "remove"},
+		{[]string{"rem", "1"}, "remove"},
+	}
+	for _, test := range tests {
+		ran = ""
+		assert.NoError(t, c.Run(test.args), "%v", test.args)
+		assert.Equal(t, ran, test.ran, "%v", test.args)
+	}
+
+	err := c.Run([]string{"pro", "c", "x"})
+	var mismatch *MismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Kind, MismatchAmbiguous)
+		assert.Equal(t, mismatch.Candidates, []string{"copy", "create"})
+		assert.Equal(t, err.Error(), "argument 2 'c' is ambiguous, as it could be copy or create")
+		assert.True(t, errors.Is(err, ErrUsage))
+	}
+
+	_, err = c.Resolve([]string{"show", "al"})
+	assert.Equal(t, err.Error(), "argument 2 'al' is ambiguous, as it could be all or allow")
+
+	assert.Equal(t, joinAlternatives([]string{"a", "b", "c"}), "a, b or c")
+
+}
@@ -0,0 +1,224 @@
+package commander
+
+import (
+	"fmt"
+	"github.com/stretchr/objx"
+)
+
+// maxAnalysedArgs is the most combinations of arguments tried when working out
+// if a command can ever be run
+const maxAnalysedArgs int = 256
+
+// Severity is how serious a problem found in a definition is
+type Severity int
+
+const (
+	// SeverityWarning means the command can be mapped, but may not behave as
+	// intended
+	SeverityWarning Severity = iota + 1
+
+	// SeverityError means the command cannot be mapped
+	SeverityError
+)
+
+// String gets a description of the severity, such as "warning"
+func (s Severity) String() string {
+
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return "unknown"
+
+}
+
+// Diagnostic describes a problem found in the definition of a command, on its
+// own or alongside the commands already mapped
+type Diagnostic struct {
+	// Severity is how serious the problem is
+	Severity Severity
+
+	// Definition is the definition of the command with the problem
+	Definition string
+
+	// Column is the column of the definition the problem is at, starting from
+	// 1, or 0 if the problem is with the definition as a whole
+	Column int
+
+	// Other is the definition of the mapped command it conflicts with, if the
+	// problem is a conflict
+	Other string
+
+	// Message describes the problem
+	Message string
+}
+
+// String gets the severity of the diagnostic and a description of the
+// problem, for example `warning: definition "show name=(string)": ...`
+func (d Diagnostic) String() string {
+	return d.Severity.String() + ": " + d.err().Error()
+}
+
+// err gets the diagnostic as a *DefinitionError
+func (d Diagnostic) err() *DefinitionError {
+	return &DefinitionError{Definition: d.Definition, Column: d.Column, Message: d.Message}
+}
+
+// diagnose makes an error diagnostic from an error making a command
+func diagnose(definition string, err error) Diagnostic {
+
+	if definitionErr, ok := err.(*DefinitionError); ok {
+		return Diagnostic{Severity: SeverityError, Definition: definitionErr.Definition, Column: definitionErr.Column, Message: definitionErr.Message}
+	}
+	return Diagnostic{Severity: SeverityError, Definition: definition, Message: err.Error()}
+
+}
+
+// Analyze checks the definition for problems, on its own and alongside the
+// commands already mapped, without mapping it. Map returns the first error
+// Analyze would find, and records any warnings, which can be got from
+// Diagnostics.
+//
+// Errors are definitions that cannot be parsed, such as those using capture
+// types that are not registered, that have the same signature as a mapped
+// command or would be just as specific for some arguments (see Map), and that
+// have defaults that are not valid. Warnings are captures that shadow
+// or are shadowed by those of a different type in another command, such as a
+// (string) capture and an (int) capture in the same place, captures with
+// patterns that may match the same arguments, and commands that can never be
+// run as more specific commands represent all of their arguments.
+func (c *Commander) Analyze(definition string, opts ...MapOption) []Diagnostic {
+
+	cmd, err := makeCommandE(definition, "", "", func(objx.Map) error { return nil }, opts...)
+	if err != nil {
+		return []Diagnostic{diagnose(definition, err)}
+	}
+	cmd.commander = c
+	return c.analyze(cmd)
+
+}
+
+// Diagnostics gets the warnings found when the commands were mapped.
+//
+// See Analyze.
+func (c *Commander) Diagnostics() []Diagnostic {
+	return c.diagnostics
+}
+
+// analyze checks the command for problems, on its own and alongside the
+// commands already mapped
+func (c *Commander) analyze(newCommand *command) []Diagnostic {
+
+	var diagnostics []Diagnostic
+	report := func(severity Severity, other *command, format string, a ...interface{}) {
+		diagnostic := Diagnostic{Severity: severity, Definition: newCommand.definition, Message: fmt.Sprintf(format, a...)}
+		if other != nil {
+			diagnostic.Other = other.definition
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+
+	if err := newCommand.checkDefaults(); err != nil {
+		diagnostics = append(diagnostics, diagnose(newCommand.definition, err))
+	}
+
+	for _, cmd := range c.commands {
+		switch {
+		case cmd.isEqualTo(newCommand):
+			report(SeverityError, cmd, "it has the same signature as (%s)", cmd.definition)
+		case cmd.isAmbiguousWith(newCommand):
+			report(SeverityError, cmd, "it is ambiguous with (%s), as they can represent the same arguments just as specifically", cmd.definition)
+		case cmd.overlapsWith(newCommand, capturesOverlap):
+			report(SeverityWarning, cmd, "it has captures of a different type to those of (%s) that can represent the same arguments, so the more specific command is chosen", cmd.definition)
+		case cmd.overlapsWith(newCommand, capturesMayOverlap):
+			report(SeverityWarning, cmd, "it has captures with patterns that may represent the same arguments as those of (%s), in which case the command mapped first is chosen", cmd.definition)
+		}
+	}
+
+	if len(diagnostics) == 0 && !c.canRun(newCommand) {
+		report(SeverityWarning, nil, "it can never be run, as more specific commands represent all of its arguments")
+	}
+
+	return diagnostics
+
+}
+
+// capturesOverlap determines if a and b could both represent the same argument,
+// allowing captures of a different specificity
+func capturesOverlap(a, b *argument) bool {
+
+	if a.specificity() != b.specificity() && (!a.isCapture() || !b.isCapture()) {
+		return false
+	}
+	return a.overlaps(b)
+
+}
+
+// capturesMayOverlap determines if a and b might both represent the same
+// argument, allowing captures with patterns that cannot be shown not to
+func capturesMayOverlap(a, b *argument) bool {
+
+	if a.specificity() != b.specificity() && (!a.isCapture() || !b.isCapture()) {
+		return false
+	}
+	return a.mayOverlap(b)
+
+}
+
+// canRun determines if the command would be chosen for any arguments, if it
+// were mapped alongside the commands already mapped. Only commands made up of
+// literals, lists and string captures are checked, by trying each of the
+// arguments they represent, as the values of other captures cannot be known.
+func (c *Commander) canRun(newCommand *command) bool {
+
+	if newCommand.isDefaultCommand() {
+		return true
+	}
+
+	commands := append(c.commands[:len(c.commands):len(c.commands)], newCommand)
+	tried := 0
+
+	for _, arguments := range newCommand.variants() {
+
+		candidates := [][]string{nil}
+		for _, a := range arguments {
+			var values []string
+			switch {
+			case a.isLiteral():
+				values = []string{a.literal}
+			case a.isList():
+				values = a.list
+			case a.specificity() == specificityString:
+				// a value no literal or list is likely to have
+				values = []string{"\x00"}
+			default:
+				return true
+			}
+			var next [][]string
+			for _, candidate := range candidates {
+				for _, value := range values {
+					next = append(next, append(candidate[:len(candidate):len(candidate)], value))
+				}
+			}
+			candidates = next
+		}
+
+		for _, args := range candidates {
+			if len(args) == 0 {
+				continue
+			}
+			if tried++; tried > maxAnalysedArgs {
+				return true
+			}
+			if mostSpecific(commands, args) == newCommand {
+				return true
+			}
+		}
+
+	}
+
+	return false
+
+}
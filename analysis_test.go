@@ -0,0 +1,114 @@
+package commander
+
+import (
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestAnalysis_Analyze(t *testing.T) {
+
+	c := New()
+	c.Map("get kind=a|b", "", "", HandlerFunc)
+	c.Map("show id=(int)", "", "", HandlerFunc)
+	c.Map("open all", "", "", HandlerFunc)
+	c.Map("open none", "", "", HandlerFunc)
+	c.Map("find n=(int:1..4)", "", "", HandlerFunc)
+	c.Map("tag a=(string:/^a/)", "", "", HandlerFunc)
+
+	tests := []struct {
+		definition string
+		severity   Severity
+		column     int
+		other      string
+		message    string
+	}{
+		{"get kind=b|c", SeverityError, 0, "get kind=a|b",
+			"it is ambiguous with (get kind=a|b), as they can represent the same arguments just as specifically"},
+		{"get kind=a|b", SeverityError, 0, "get kind=a|b",
+			"it has the same signature as (get kind=a|b)"},
+		{"get kind=b|a", SeverityError, 0, "get kind=a|b",
+			"it has the same signature as (get kind=a|b)"},
+		{"show name=(string)", SeverityWarning, 0, "show id=(int)",
+			"it has captures of a different type to those of (show id=(int)) that can represent the same arguments, so the more specific command is chosen"},
+		{"open which=all|none", SeverityWarning, 0, "",
+			"it can never be run, as more specific commands represent all of its arguments"},
+		{"list name=(string", SeverityError, 11, "",
+			"the capture type is not closed with )"},
+		{"list [n=(int)=many]", SeverityError, 7, "",
+			"the default many of n is not a valid int: not a whole number"},
+		{"list n=(unknown)", SeverityError, 9, "",
+			"the capture type (unknown) is not registered"},
+		{"show n=(uint)", SeverityError, 0, "show id=(int)",
+			"it is ambiguous with (show id=(int)), as they can represent the same arguments just as specifically"},
+		{"show n=(float)", SeverityError, 0, "show id=(int)",
+			"it is ambiguous with (show id=(int)), as they can represent the same arguments just as specifically"},
+		{"show n=(string:/^[0-9]+$/)", SeverityError, 0, "show id=(int)",
+			"it is ambiguous with (show id=(int)), as they can represent the same arguments just as specifically"},
+		{"find m=(int:3..20)", SeverityError, 0, "find n=(int:1..4)",
+			"it is ambiguous with (find n=(int:1..4)), as they can represent the same arguments just as specifically"},
+		{"tag b=(string:/^b/)", SeverityWarning, 0, "tag a=(string:/^a/)",
+			"it has captures with patterns that may represent the same arguments as those of (tag a=(string:/^a/)), in which case the command mapped first is chosen"},
+	}
+
+	for _, test := range tests {
+		diagnostics := c.Analyze(test.definition)
+		if assert.Equal(t, len(diagnostics), 1, test.definition) {
+			assert.Equal(t, diagnostics[0], Diagnostic{test.severity, test.definition, test.column, test.other, test.message})
+		}
+	}
+
+	assert.Empty(t, c.Analyze("get kind=c|d"))
+	assert.Empty(t, c.Analyze("show at=(time)"))
+	assert.Empty(t, c.Analyze("open which=all|some"))
+	assert.Empty(t, c.Analyze("list [name=(string)]"))
+	assert.Empty(t, c.Analyze("find m=(int:5..20)"))
+	assert.Empty(t, c.Analyze("find m=(float:4.5..)"))
+	assert.Empty(t, c.Analyze("find s=(string:5..)"))
+	assert.Empty(t, c.Diagnostics())
+
+}
+
+func TestAnalysis_Map(t *testing.T) {
+
+	c := New()
+	c.Map("show id=(int)", "", "", HandlerFunc)
+	c.Map("show name=(string)", "", "", HandlerFunc)
+
+	diagnostics := c.Diagnostics()
+	if assert.Equal(t, len(diagnostics), 1) {
+		assert.Equal(t, diagnostics[0].Severity, SeverityWarning)
+		assert.Equal(t, diagnostics[0].Definition, "show name=(string)")
+		assert.Equal(t, diagnostics[0].Other, "show id=(int)")
+		assert.Contains(t, diagnostics[0].String(), "warning: definition \"show name=(string)\": it has captures")
+	}
+
+	assert.Error(t, c.Map("show other=(string)", "", "", HandlerFunc))
+	assert.Error(t, c.Map("list name=(string", "", "", HandlerFunc))
+	assert.Equal(t, len(c.Diagnostics()), 1)
+
+	assert.Equal(t, SeverityError.String(), "error")
+
+	// captures of different types that accept the same values are ambiguous,
+	// whichever is mapped first
+	for _, pair := range [][]string{{"int", "uint"}, {"uint", "int"}, {"int", "float"}, {"float", "int"}} {
+		c := New()
+		assert.NoError(t, c.Map("show a=("+pair[0]+")", "", "", HandlerFunc))
+		assert.Error(t, c.Map("show b=("+pair[1]+")", "", "", HandlerFunc), "%v", pair)
+	}
+
+	// ranges that do not overlap tell commands apart
+	c = New()
+	var ran string
+	assert.NoError(t, c.Map("show n=(int:1..4)", "", "", func(objx.Map) {
+		ran = "small"
+	}))
+	assert.NoError(t, c.Map("show m=(int:5..20)", "", "", func(objx.Map) {
+		ran = "large"
+	}))
+	assert.NoError(t, c.Run([]string{"show", "3"}))
+	assert.Equal(t, ran, "small")
+	assert.NoError(t, c.Run([]string{"show", "7"}))
+	assert.Equal(t, ran, "large")
+
+}
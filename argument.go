@@ -1,11 +1,11 @@
 package commander
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
-	"strconv"
+	"github.com/stretchr/commander/syntax"
+	"reflect"
 	"strings"
-	"time"
 )
 
 /*
@@ -14,20 +14,6 @@ const PathSegmentRegexString = `(?:[A-Za-z0-9\-._~!$&'()*+,;=:@]|%[0-9A-Fa-f]{2}
 var PathSegmentRegex = regexp.MustCompile(PathSegmentRegexString)
 */
 
-var (
-	// literalRegex represents the regexp object for literals.
-	literalRegex = regexp.MustCompile(`^[^=|()\[\]]+$`)
-
-	// listRegex represents the regexp for lists
-	listRegex = regexp.MustCompile(`^[^=|()\[\]]+=[^=|()\[\]]+(?:\|[^=|()\[\]]+)+$`)
-
-	// captureRegex represents the regexp for captures.
-	captureRegex = regexp.MustCompile(fmt.Sprintf(`^(?P<%s>[\[])?(?P<%s>[^=|()\[\]]+)=\((?P<%s>[^=|()\[\]]+)\)(?P<%s>\.\.\.)?(?P<%s>[\]])?$`,
-		submatchKeyOpen, submatchKeyKind, submatchKeyType, submatchKeyVariable, submatchKeyClose))
-	// captureSubmatchNames represents the regexp for capture sub matches.
-	captureSubmatchNames = captureRegex.SubexpNames()
-)
-
 type argument struct {
 	// rawArg is a string containing the argument in its raw form
 	rawArg string
@@ -35,30 +21,69 @@ type argument struct {
 	// literal is a string containing the text of the literal argument
 	literal string
 
+	// aliases contains the other names the literal may be given by
+	aliases []string
+
 	// list is an array containing each of the arguments in a list
 	list []string
 
+	// listHelp contains the help text for the items in the list, if any
+	listHelp map[string]string
+
 	// identifier is a string containing the identifier of the argument
 	identifier string
 
 	// captureType is a string containing the capture type of the argument
 	captureType string
 
+	// constraint restricts the values the capture accepts, or is nil if it
+	// accepts any value of the capture type
+	constraint *constraint
+
+	// column is the column of the definition the argument starts at
+	column int
+
 	// isOptional is a bool used to determine if this argument is optional
 	optional bool
 
+	// defaultValue is the value used for the argument if it is not given, or
+	// an empty string if there is none
+	defaultValue string
+
 	// isVariable is a bool used to determine if this argument is variable
 	variable bool
-}
 
-// containsKey determines if a map[string]string contains string key
-func containsKey(stringMap map[string]string, key string) bool {
+	// command is the command the argument is in, whose settings are used to
+	// convert captures
+	command *command
+}
 
-	if _, ok := stringMap[key]; ok {
-		return true
+// ListHelp gives help text for the items in the list with the identifier,
+// which is shown under the list in the help for the command. For example,
+// for the definition "create kind=project|account name=(string)":
+//
+//	ListHelp("kind", map[string]string{
+//		"project": "A project, which holds accounts",
+//		"account": "An account, which belongs to a project",
+//	})
+//
+// Map returns an error if there is no list with the identifier, or help is
+// given for an item that is not in the list.
+func ListHelp(identifier string, help map[string]string) MapOption {
+	return func(cmd *command) error {
+		for _, a := range cmd.arguments {
+			if a.isList() && a.identifier == identifier {
+				for item := range help {
+					if !containsString(a.list, item) {
+						return fmt.Errorf("help was given for %s, which is not in the list %s", item, identifier)
+					}
+				}
+				a.listHelp = help
+				return nil
+			}
+		}
+		return fmt.Errorf("help was given for the list %s, which is not in the definition", identifier)
 	}
-	return false
-
 }
 
 // constainsString determines if a []string contains string
@@ -73,168 +98,219 @@ func containsString(stringSlice []string, contains string) bool {
 
 }
 
-// slicesAreEqual determines if two string slices are equal
+// slicesAreEqual determines if two string slices contain the same items, in
+// any order
 func slicesAreEqual(left, right []string) bool {
 	if len(left) != len(right) {
 		return false
 	}
-	for i := 0; i < len(left); i++ {
-		if left[i] != right[i] {
+	for _, item := range left {
+		if !containsString(right, item) {
+			return false
+		}
+	}
+	for _, item := range right {
+		if !containsString(left, item) {
 			return false
 		}
 	}
 	return true
 }
 
-// canCastToType determines if the cmdArg can be cast to a given type
-func canCastToType(cmdArg, castType string) bool {
-	if castToType(cmdArg, castType) == nil {
-		return false
+// convertToType converts the cmdArg into a value of the given type, returning
+// an error describing why if the cmdArg cannot be represented by that type
+func convertToType(cmdArg, castType string) (interface{}, error) {
+
+	t := lookupType(castType)
+	if t == nil {
+		return nil, fmt.Errorf("the capture type (%s) has not been registered", castType)
 	}
-	return true
+
+	value, err := t.parse(cmdArg)
+	if err == nil && value == nil {
+		err = errors.New("no value")
+	}
+	return value, err
+
 }
 
-func castToType(cmdArg, castType string) interface{} {
+// makeArgumentFrom makes a new argument from the literal, list or capture
+// node of a parsed definition, which is optional if it is in an optional group
+func makeArgumentFrom(node syntax.Node, optional bool) *argument {
 
-	switch castType {
-	case "string":
-		return cmdArg
-	case "int":
-		if value, err := strconv.ParseInt(cmdArg, 10, 0); err != nil {
-			return nil
-		} else {
-			return value
-		}
-	case "int64":
-		if value, err := strconv.ParseInt(cmdArg, 10, 64); err != nil {
-			return nil
-		} else {
-			return value
-		}
-	case "uint":
-		if value, err := strconv.ParseUint(cmdArg, 10, 0); err != nil {
-			return nil
-		} else {
-			return value
-		}
-	case "uint64":
-		if value, err := strconv.ParseUint(cmdArg, 10, 64); err != nil {
-			return nil
-		} else {
-			return value
-		}
-	case "bool":
-		if value, err := strconv.ParseBool(cmdArg); err != nil {
-			return nil
-		} else {
-			return value
-		}
-	case "time":
-		if value, err := time.Parse(time.ANSIC, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.UnixDate, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RubyDate, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC822, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC822Z, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC850, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC1123, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC1123Z, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC3339, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.RFC3339Nano, cmdArg); err == nil {
-			return value
-		}
-		if value, err := time.Parse(time.Kitchen, cmdArg); err == nil {
-			return value
-		}
-		return nil
+	a := new(argument)
+	a.optional = optional
+
+	switch node := node.(type) {
+	case *syntax.Literal:
+		a.literal = node.Value
+	case *syntax.List:
+		a.identifier = node.Identifier
+		a.list = node.Items
+	case *syntax.Capture:
+		a.identifier = node.Identifier
+		a.captureType = node.Type
+		a.constraint = makeConstraint(node.Type, node.Constraint, constraintColumn(node.TypeColumn, node.Type))
+		a.variable = node.Variable
+		a.defaultValue = node.Default
+	default:
+		return a
 	}
+	a.rawArg = node.String()
+	a.column = node.Pos()
 
-	return nil
+	return a
 
 }
 
-// mapSubmatchNames creates a map of named matches and their contents
-func mapSubmatchNames(submatchNames []string, submatches []string) map[string]string {
+// represents determines if this argument represents the cmdArg string
+func (a *argument) represents(cmdArg string) bool {
 
-	var mappedSubexpNames = make(map[string]string)
+	switch {
+	case a.isLiteral() && (containsString(a.names(), cmdArg) || a.isAbbreviatedBy(cmdArg)):
+		return true
+	case a.isList() && containsString(a.list, cmdArg):
+		return true
+	case a.isCapture():
+		_, err := a.convert(cmdArg)
+		return err == nil
+	}
+
+	return false
 
-	for i := 1; i < len(submatchNames); i++ {
-		if submatches[i] != "" {
-			mappedSubexpNames[submatchNames[i]] = submatches[i]
+}
+
+// names gets the literal of this argument followed by its aliases, or nil if
+// it is not a literal
+func (a *argument) names() []string {
+
+	if !a.isLiteral() {
+		return nil
+	}
+	return append([]string{a.literal}, a.aliases...)
+
+}
+
+// isAbbreviatedBy determines if the cmdArg is a prefix of the literal of this
+// argument or one of its aliases, but not one of them in full, when the
+// commander it was mapped on uses prefix matching
+func (a *argument) isAbbreviatedBy(cmdArg string) bool {
+
+	if cmdArg == "" || a.command == nil || a.command.commander == nil ||
+		!a.command.commander.prefixMatching || containsString(a.names(), cmdArg) {
+		return false
+	}
+	for _, name := range a.names() {
+		if strings.HasPrefix(name, cmdArg) {
+			return true
 		}
 	}
+	return false
 
-	return mappedSubexpNames
+}
+
+// value gets the value this argument holds for the cmdArg string. Captures are
+// converted to their capture type, literals and lists are left as strings.
+func (a *argument) value(cmdArg string) interface{} {
+
+	if a.isCapture() {
+		value, _ := a.convert(cmdArg)
+		return value
+	}
+	return cmdArg
 
 }
 
-func (a *argument) parseArgument() {
+// values gets the values this argument holds for each of the cmdArgs, as a
+// slice of the converted type. For example, a "(string)..." capture yields
+// a []string, and an "(int)..." capture yields an []int64.
+func (a *argument) values(cmdArgs []string) interface{} {
 
-	switch {
-	case literalRegex.MatchString(a.rawArg):
-		a.literal = a.rawArg
-	case listRegex.MatchString(a.rawArg):
-		parts := strings.Split(a.rawArg, delimiterEquality)
-		a.identifier = parts[0]
-		a.list = strings.Split(parts[1], delimiterListItems)
-	case captureRegex.MatchString(a.rawArg):
-		submatches := captureRegex.FindStringSubmatch(a.rawArg)
-		submatchMap := mapSubmatchNames(captureSubmatchNames, submatches)
-
-		a.identifier = submatchMap[submatchKeyKind]
-		a.captureType = submatchMap[submatchKeyType]
-
-		if containsKey(submatchMap, submatchKeyOpen) && containsKey(submatchMap, submatchKeyClose) {
-			a.optional = true
-		}
-		if containsKey(submatchMap, submatchKeyVariable) {
-			a.variable = true
-		}
+	if !a.isCapture() {
+		return cmdArgs
 	}
+	return castValues(cmdArgs, a.convert)
 
 }
 
-func makeArgument(rawArg string) *argument {
+// convert converts the cmdArg into a value of the capture type of this argument
+func (a *argument) convert(cmdArg string) (interface{}, error) {
 
-	a := new(argument)
-	a.rawArg = rawArg
+	return a.command.convert(cmdArg, a.captureType, a.constraint)
 
-	a.parseArgument()
+}
 
-	return a
+// castValues converts each of the cmdArgs using convert, returning them as a
+// slice of the type they are converted to, or as an []interface{} if they are
+// converted to different types
+func castValues(cmdArgs []string, convert func(cmdArg string) (interface{}, error)) interface{} {
+
+	if len(cmdArgs) == 0 {
+		return nil
+	}
+
+	converted := make([]interface{}, len(cmdArgs))
+	sameType := true
+	for i, cmdArg := range cmdArgs {
+		converted[i], _ = convert(cmdArg)
+		sameType = sameType && reflect.TypeOf(converted[i]) == reflect.TypeOf(converted[0])
+	}
+	if !sameType || converted[0] == nil {
+		return converted
+	}
+
+	values := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(converted[0])), 0, len(converted))
+	for _, value := range converted {
+		values = reflect.Append(values, reflect.ValueOf(value))
+	}
+	return values.Interface()
 
 }
 
-// represents determines if this argument represents the cmdArg string
-func (a *argument) represents(cmdArg string) bool {
+// expected describes what this argument expects, for use in mismatch errors
+func (a *argument) expected() string {
 
 	switch {
-	case a.isLiteral() && a.literal == cmdArg:
-		return true
-	case a.isList() && containsString(a.list, cmdArg):
-		return true
-	case a.isCapture() && canCastToType(cmdArg, a.captureType):
-		return true
+	case a.isLiteral():
+		return fmt.Sprintf("'%s'", a.literal)
+	case a.isList():
+		return "one of " + strings.Join(a.list, delimiterListItems)
 	}
+	return describeCapture(a.captureType, a.constraint)
 
-	return false
+}
+
+// conversionError gets the error converting the cmdArg to the capture type of
+// this argument, or nil if it is not a capture or the cmdArg converts
+func (a *argument) conversionError(cmdArg string) error {
+
+	if !a.isCapture() {
+		return nil
+	}
+	_, err := a.convert(cmdArg)
+	return err
+
+}
+
+// describeCapture describes a value of the capture type that satisfies the
+// constraint, if there is one
+func describeCapture(captureType string, c *constraint) string {
+
+	if c == nil {
+		return describeType(captureType)
+	}
+	return describeType(captureType) + " " + c.describe()
+
+}
+
+// describeType describes a value of the capture type, for use in help and
+// mismatch errors
+func describeType(captureType string) string {
+
+	if t := lookupType(captureType); t != nil && t.description != "" {
+		return t.description
+	}
+	return "a valid " + captureType
 
 }
 
@@ -268,6 +344,112 @@ func (a *argument) isVariable() bool {
 
 }
 
+// specificity ranks how specific the argument is, so that when the arguments
+// given on the command line match more than one command, the most specific
+// command is run. Literals are the most specific, then lists, then captures of
+// any type other than string (or with a constraint), then string captures.
+func (a *argument) specificity() int {
+
+	switch {
+	case a.isLiteral():
+		return specificityLiteral
+	case a.isList():
+		return specificityList
+	case a.captureType != "string" || a.constraint != nil:
+		return specificityTyped
+	}
+	return specificityString
+
+}
+
+// specificityOf ranks how specific the argument is for the cmdArg it
+// represents, which is the same as specificity unless the cmdArg is a prefix
+// of the literal, which is the least specific
+func (a *argument) specificityOf(cmdArg string) int {
+
+	if a.isAbbreviatedBy(cmdArg) {
+		return specificityPrefix
+	}
+	return a.specificity()
+
+}
+
+// overlapProbes are values tried, along with the bounds of their ranges, to
+// find out if two captures can represent the same command line argument
+var overlapProbes = []string{"0", "1", "-1", "42", "1.5", "true", "false", "1s", "2006-01-02", "now"}
+
+// overlaps determines if this argument and arg could both represent the same
+// command line argument. A string capture without a constraint overlaps any
+// other capture, and other captures overlap if a value is found that both
+// accept, such as 0 for an (int) and a (float), or a bound that two ranges
+// share.
+func (a *argument) overlaps(arg *argument) bool {
+
+	switch {
+	case a.isCapture() && !arg.isCapture():
+		return arg.overlaps(a)
+	case a.isLiteral() && !arg.isLiteral():
+		for _, name := range a.names() {
+			if arg.isList() && containsString(arg.list, name) || arg.isCapture() && arg.represents(name) {
+				return true
+			}
+		}
+		return false
+	case a.isLiteral():
+		for _, name := range a.names() {
+			if containsString(arg.names(), name) {
+				return true
+			}
+		}
+		return false
+	case a.isList() && arg.isLiteral():
+		return arg.overlaps(a)
+	case a.isList():
+		for _, item := range a.list {
+			if arg.isList() && containsString(arg.list, item) || arg.isCapture() && arg.represents(item) {
+				return true
+			}
+		}
+		return false
+	case a.isCapture() && arg.isCapture():
+		return a.specificity() == specificityString || arg.specificity() == specificityString ||
+			a.acceptsSameValue(arg)
+	}
+	return false
+
+}
+
+// acceptsSameValue determines if this capture and arg both accept one of the
+// overlap probes or the bounds of their ranges
+func (a *argument) acceptsSameValue(arg *argument) bool {
+
+	probes := append(append(a.constraint.bounds(), arg.constraint.bounds()...), overlapProbes...)
+	for _, probe := range probes {
+		if a.represents(probe) && arg.represents(probe) {
+			return true
+		}
+	}
+	return false
+
+}
+
+// mayOverlap determines if this argument and arg might represent the same
+// command line argument, which they might if they overlap, or if they are
+// captures and either has a pattern, as the values a pattern matches cannot
+// all be tried
+func (a *argument) mayOverlap(arg *argument) bool {
+
+	return a.overlaps(arg) ||
+		a.isCapture() && arg.isCapture() && (a.constraint.isPattern() || arg.constraint.isPattern())
+
+}
+
+func (a *argument) hasDefault() bool {
+
+	return a.defaultValue != ""
+
+}
+
 func (a *argument) isEqualTo(arg *argument) bool {
 
 	switch {
@@ -277,7 +459,8 @@ func (a *argument) isEqualTo(arg *argument) bool {
 		return slicesAreEqual(a.list, arg.list)
 	case a.isCapture() && arg.isCapture():
 		return a.captureType == arg.captureType &&
-			a.identifier == arg.identifier
+			a.identifier == arg.identifier &&
+			a.constraint.String() == arg.constraint.String()
 	}
 	return false
 
@@ -3,6 +3,7 @@ package commander
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 const (
@@ -25,6 +26,18 @@ var argArray = []string{argLiteral, argList, argListLong, argCaptureType,
 	argCaptureTypeInt, argCaptureTypeInt64, argCaptureTypeUint,
 	argCaptureTypeUint64, argCaptureTypeBool, argCaptureTypeTime}
 
+// makeArgument makes a new argument from the rawArg, which defines a single
+// literal, list or capture, optionally in [ ] square brackets
+func makeArgument(rawArg string) *argument {
+
+	node, optional := singleNode(rawArg)
+	a := makeArgumentFrom(node, optional)
+	a.rawArg = rawArg
+
+	return a
+
+}
+
 func TestArgument_MakeArgument(t *testing.T) {
 
 	a := makeArgument("rawArg")
@@ -206,3 +219,33 @@ func TestArgument_isEqualTo(t *testing.T) {
 		}
 	}
 }
+
+func TestArgument_Value(t *testing.T) {
+
+	assert.Equal(t, makeArgument(argList).value("project"), "project")
+	assert.Equal(t, makeArgument(argCaptureType).value("mat"), "mat")
+	assert.Equal(t, makeArgument(argCaptureTypeInt).value("-123"), int64(-123))
+	assert.Equal(t, makeArgument(argCaptureTypeInt64).value("123"), int64(123))
+	assert.Equal(t, makeArgument(argCaptureTypeUint).value("123"), uint64(123))
+	assert.Equal(t, makeArgument(argCaptureTypeUint64).value("123"), uint64(123))
+	assert.Equal(t, makeArgument(argCaptureTypeBool).value("false"), false)
+
+	value := makeArgument(argCaptureTypeTime).value("Mon Jan  2 15:04:05 2006")
+	if assert.IsType(t, time.Time{}, value) {
+		assert.Equal(t, value.(time.Time).Year(), 2006)
+	}
+
+}
+
+func TestArgument_Values(t *testing.T) {
+
+	a := makeArgument(argVariableCaptureType)
+	assert.Equal(t, a.values([]string{"mat", "tyler"}), []string{"mat", "tyler"})
+
+	a = makeArgument("nums=(int)...")
+	assert.Equal(t, a.values([]string{"1", "-2", "3"}), []int64{1, -2, 3})
+
+	a = makeArgument("flags=(bool)...")
+	assert.Equal(t, a.values([]string{"true", "false"}), []bool{true, false})
+
+}
@@ -1,6 +1,8 @@
 package commander
 
 import (
+	"fmt"
+	"github.com/stretchr/commander/syntax"
 	"github.com/stretchr/objx"
 	"strings"
 )
@@ -9,6 +11,16 @@ import (
 // to be called when a command is matched.
 type Handler func(args objx.Map)
 
+// ErrorHandler is a func type that defines the function signature of a handler
+// that can fail. The error it returns is passed back from Run, and Go turns it
+// into a non-zero exit status.
+type ErrorHandler func(args objx.Map) error
+
+// MapOption is a func type that changes how a command is matched or its
+// arguments are converted, such as TimeLayouts. MapOptions are given when
+// the command is mapped, and return an error if they cannot be applied to it.
+type MapOption func(cmd *command) error
+
 // command is a type used to create and manage individual command strings
 type command struct {
 	// definition is the original string contining the command definition
@@ -20,24 +32,76 @@ type command struct {
 	// description is a string containing a description of this command
 	description string
 
-	// handler is the Handler associated with this command
-	handler Handler
+	// handler is the ErrorHandler associated with this command
+	handler ErrorHandler
 
-	// arguments is an array of all the arguments in the command string
+	// arguments is an array of all the positional arguments in the command string
 	arguments []*argument
 
-	// numOptional contains the number of optional arguments in this command
-	numOptional int
+	// options is an array of all the options (named flags) in the command string
+	options []*option
+
+	// aliases contains the other names of the command, which replace the
+	// last words of its name
+	aliases []string
+
+	// optionals contains the optional groups of arguments, which may be left
+	// out together. Groups nested in another come before it.
+	optionals []optionalGroup
 
 	// defaultCommand holds whether this is the default command or not
 	defaultCommand bool
+
+	// hidden holds whether this command is left out of the usage and completion
+	hidden bool
+
+	// group is the group the command was mapped in, or nil if it was mapped at
+	// the top level
+	group *Group
+
+	// commander is the Commander the command was mapped on
+	commander *Commander
+
+	// times holds the settings used to convert the (time) captures of this
+	// command, where they differ from those of the commander
+	times timeSettings
+
+	// env contains the environment variables arguments and options are bound
+	// to by identifier, with an empty name for those named after the
+	// identifier
+	env map[string]string
+}
+
+// optionalGroup is a group of arguments in [ ] square brackets in a definition
+type optionalGroup struct {
+	// start is the index of the first argument in the group
+	start int
+
+	// end is the index of the argument after the last one in the group
+	end int
+}
+
+// makeCommand makes a new Command object and sets it up appropriately,
+// returning a *DefinitionError if the definition is not valid
+func makeCommand(definition, summary, description string, handler Handler, opts ...MapOption) (*command, error) {
+
+	if handler == nil {
+		return nil, &DefinitionError{Definition: definition, Message: "a handler must be given"}
+	}
+
+	return makeCommandE(definition, summary, description, func(args objx.Map) error {
+		handler(args)
+		return nil
+	}, opts...)
+
 }
 
-// makeCommand makes a new Command object and sets it up appropriately
-func makeCommand(definition, summary, description string, handler Handler) *command {
+// makeCommandE makes a new Command object with an ErrorHandler and sets it up
+// appropriately, returning a *DefinitionError if the definition is not valid
+func makeCommandE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) (*command, error) {
 
 	if handler == nil {
-		panic("A handler must be defined for each command registered.")
+		return nil, &DefinitionError{Definition: definition, Message: "a handler must be given"}
 	}
 
 	c := new(command)
@@ -47,72 +111,471 @@ func makeCommand(definition, summary, description string, handler Handler) *comm
 	c.description = description
 	c.summary = summary
 
-	// make the arguments
+	parsed, err := parseDefinition(definition)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.addNodes(parsed.Nodes, false); err != nil {
+		return nil, err
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, c.definitionError(0, "%s", err)
+		}
+	}
+
+	return c, nil
+
+}
+
+// definitionError makes a *DefinitionError for the problem at the column of
+// the definition of this command
+func (c *command) definitionError(column int, format string, a ...interface{}) *DefinitionError {
+	return &DefinitionError{Definition: c.definition, Column: column, Message: fmt.Sprintf(format, a...)}
+}
+
+// addNodes makes the arguments and options of this command from the nodes of
+// its parsed definition, which are optional if they are in an optional group,
+// returning a *DefinitionError if they cannot be used
+func (c *command) addNodes(nodes []syntax.Node, optional bool) error {
+
+	for _, node := range nodes {
+		switch node := node.(type) {
+		case *syntax.Optional:
+			start := len(c.arguments)
+			if err := c.addNodes(node.Nodes, true); err != nil {
+				return err
+			}
+			if len(c.arguments) > start {
+				c.optionals = append(c.optionals, optionalGroup{start, len(c.arguments)})
+			}
+		case *syntax.Option:
+			if err := c.addOption(node); err != nil {
+				return err
+			}
+		default:
+			if err := c.addArgument(node, optional); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+
+}
+
+// addOption makes an option of this command from the node
+func (c *command) addOption(node *syntax.Option) error {
+
+	if node.Type != "" {
+		if column, err := checkCaptureType(node.Type, node.TypeColumn, node.Constraint); err != nil {
+			return c.definitionError(column, "%s", err)
+		}
+	}
+
+	o := makeOptionFrom(node)
+	o.command = c
+	if o.defaultValue != "" && o.isRepeatable() {
+		return c.definitionError(o.column, "a default may not be given for %s, as it can be repeated", o.identifier)
+	}
+	for _, existing := range c.options {
+		if existing.identifier == o.identifier {
+			return c.definitionError(o.column, "the option %s is already in the definition", o.identifier)
+		}
+	}
+	c.options = append(c.options, o)
+	return nil
+
+}
 
-	argumentStrings := strings.Split(definition, delimiterArgumentSeparator)
-	c.arguments = make([]*argument, len(argumentStrings))
-	optionalFound := false
+// addArgument makes an argument of this command from the literal, list or
+// capture node
+func (c *command) addArgument(node syntax.Node, optional bool) error {
 
-	for argumentIndex, value := range strings.Split(definition, delimiterArgumentSeparator) {
-		c.arguments[argumentIndex] = makeArgument(value)
-		if !c.arguments[argumentIndex].isOptional() && optionalFound {
-			panic("An optional argument may not precede a required argument")
-		} else if c.arguments[argumentIndex].isOptional() {
-			c.numOptional++
-			optionalFound = true
+	if capture, ok := node.(*syntax.Capture); ok {
+		if column, err := checkCaptureType(capture.Type, capture.TypeColumn, capture.Constraint); err != nil {
+			return c.definitionError(column, "%s", err)
 		}
-		if c.arguments[argumentIndex].isVariable() {
-			if argumentIndex != len(argumentStrings)-1 {
-				panic("A variable argument may only appear at the end of a command string")
+	}
+	if len(c.arguments) > 0 && c.arguments[len(c.arguments)-1].isVariable() {
+		return c.definitionError(node.Pos(), "a variable argument may only appear at the end, but %s is followed by %s", c.arguments[len(c.arguments)-1].identifier, node)
+	}
+
+	a := makeArgumentFrom(node, optional)
+	a.command = c
+	if a.hasDefault() && (!a.isOptional() || a.isVariable()) {
+		return c.definitionError(a.column, "a default may only be given for an optional argument that is not variable, not %s", a.identifier)
+	}
+	c.arguments = append(c.arguments, a)
+	return nil
+
+}
+
+// checkDefaults ensures the defaults of the arguments and options of this
+// command are valid values, returning a *DefinitionError if any are not. It is
+// called once the MapOptions have been applied, so the constraints of times
+// are parsed here, with the time settings of the command.
+func (c *command) checkDefaults() error {
+
+	for _, a := range c.arguments {
+		if err := c.parseTimeConstraint(a.captureType, a.constraint); err != nil {
+			return err
+		}
+	}
+	for _, o := range c.options {
+		if err := c.parseTimeConstraint(o.captureType, o.constraint); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range c.arguments {
+		if !a.hasDefault() {
+			continue
+		}
+		if _, err := a.convert(a.defaultValue); err != nil {
+			return c.definitionError(a.column, "the default %s of %s is not %s: %s", a.defaultValue, a.identifier, a.expected(), err)
+		}
+	}
+	for _, o := range c.options {
+		if o.defaultValue == "" {
+			continue
+		}
+		if _, err := o.convert(o.defaultValue); err != nil {
+			return c.definitionError(o.column, "the default %s of %s is not %s: %s", o.defaultValue, o.identifier, describeCapture(o.captureType, o.constraint), err)
+		}
+	}
+	return nil
+
+}
+
+// parseTimeConstraint converts the bounds of the constraint of a time capture
+// with the time settings of this command, returning a *DefinitionError if the
+// constraint is not valid
+func (c *command) parseTimeConstraint(captureType string, constraint *constraint) error {
+
+	if captureType != timeType || constraint == nil {
+		return nil
+	}
+	parsed, err := parseConstraint(captureType, constraint.raw, c)
+	if err != nil {
+		return c.definitionError(constraint.column, "%s", err)
+	}
+	parsed.column = constraint.column
+	*constraint = *parsed
+	return nil
+
+}
+
+// convert converts the cmdArg into a value of the given type that satisfies
+// the constraint (if it is not nil), using the time settings of the command
+// (and the commander it was mapped on) for times
+func (c *command) convert(cmdArg, castType string, constraint *constraint) (interface{}, error) {
+
+	var value interface{}
+	var err error
+
+	if castType == timeType {
+		settings := defaultTimeSettings
+		if c != nil {
+			if c.commander != nil {
+				settings = settings.with(c.commander.times)
 			}
+			settings = settings.with(c.times)
 		}
+		value, err = settings.parse(cmdArg)
+		if err == nil && constraint != nil {
+			// the bounds may be relative to now, such as -1w, or on the
+			// current day, such as 9:00AM, so they are converted for each value
+			constraint, err = parseConstraint(castType, constraint.raw, c)
+		}
+	} else {
+		value, err = convertToType(cmdArg, castType)
 	}
 
-	return c
+	if err == nil {
+		err = constraint.check(cmdArg, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
 
 }
 
-// represents determines if this command represents the array of arguments
-func (c *command) represents(rawArgs []string) (bool, int) {
+// parsedArgs holds command line arguments separated into the positional
+// arguments and the options of a command
+type parsedArgs struct {
+	// positional contains the positional arguments
+	positional []string
 
-	argIndex := 0
-	for rawArgIndex, _ := range rawArgs {
+	// positions contains the index in the command line of each of the
+	// positional arguments
+	positions []int
 
-		if argIndex == len(c.arguments)-1 && rawArgIndex != len(rawArgs)-1 {
-			if !c.arguments[argIndex].isVariable() {
-				return false, argIndex
-			} else {
-				argIndex++
-				return true, argIndex
+	// occurrences contains the values given for each option, by identifier.
+	// Switches have an empty value for each time they were given.
+	occurrences map[string][]string
+}
+
+// parseOptions separates the options of this command from the positional
+// arguments in rawArgs. Options may appear anywhere, and everything after
+// a "--" argument is positional. If the options were given incorrectly,
+// for example if a value is missing or cannot be converted, a
+// MismatchError describing the problem is returned.
+func (c *command) parseOptions(rawArgs []string) (*parsedArgs, *MismatchError) {
+
+	parsed := &parsedArgs{occurrences: make(map[string][]string)}
+	optionsEnded := len(c.options) == 0
+
+	for i := 0; i < len(rawArgs); i++ {
+
+		if !optionsEnded && rawArgs[i] == delimiterOptionsEnd {
+			optionsEnded = true
+			continue
+		}
+
+		var o *option
+		var value string
+		var hasValue bool
+		for _, candidate := range c.options {
+			if optionsEnded {
+				break
+			}
+			var represents bool
+			if represents, value, hasValue = candidate.represents(rawArgs[i]); represents {
+				o = candidate
+				break
 			}
 		}
 
-		// this is an optional argument. If we don't get a match, keep trying
-		if c.arguments[argIndex].isOptional() {
-			if c.arguments[argIndex].represents(rawArgs[rawArgIndex]) {
-				argIndex++
-				rawArgIndex++
-			} else {
-				rawArgIndex++
+		if o == nil {
+			parsed.positional = append(parsed.positional, rawArgs[i])
+			parsed.positions = append(parsed.positions, i)
+			continue
+		}
+
+		mismatch := &MismatchError{Position: i + 1, Option: rawArgs[i], command: c}
+		if hasValue {
+			mismatch.Option = rawArgs[i][:len(rawArgs[i])-len(value)-1]
+		}
+
+		if len(parsed.occurrences[o.identifier]) > 0 && !o.isRepeatable() {
+			mismatch.Kind = MismatchUnexpected
+			return nil, mismatch
+		}
+
+		switch {
+		case o.isSwitch() && hasValue:
+			mismatch.Kind = MismatchUnexpected
+			mismatch.Arg = value
+			return nil, mismatch
+		case !o.isSwitch() && !hasValue:
+			if i+1 >= len(rawArgs) {
+				mismatch.Kind = MismatchMissing
+				mismatch.Identifier = o.identifier
+				mismatch.Expected = describeCapture(o.captureType, o.constraint)
+				return nil, mismatch
 			}
-		} else {
-			if c.arguments[argIndex].represents(rawArgs[rawArgIndex]) {
-				argIndex++
-				rawArgIndex++
-			} else {
-				return false, argIndex
+			i++
+			value = rawArgs[i]
+		}
+
+		if !o.isSwitch() {
+			if _, err := o.convert(value); err != nil {
+				mismatch.Kind = MismatchInvalid
+				mismatch.Arg = value
+				mismatch.Identifier = o.identifier
+				mismatch.Expected = describeCapture(o.captureType, o.constraint)
+				mismatch.Err = err
+				return nil, mismatch
 			}
 		}
+
+		parsed.occurrences[o.identifier] = append(parsed.occurrences[o.identifier], value)
+
 	}
 
-	// We've made it this far without an explicit failure, but that doesn't mean
-	// every argument has been satisfied. Check our
+	return parsed, nil
+
+}
+
+// mismatch explains why this command does not represent the array of
+// arguments, or returns nil if it does. The explanation is given for the
+// furthest positional argument any way of matching the arguments reached.
+func (c *command) mismatch(rawArgs []string) *MismatchError {
+
+	parsed, mismatch := c.parseOptions(rawArgs)
+	if mismatch != nil {
+		return mismatch
+	}
+
+	m := c.matcher(parsed.positional)
+	if m.matchFrom(0, 0) {
+		return nil
+	}
+
+	a := m.expected
+	if m.furthest == len(parsed.positional) {
+		return &MismatchError{
+			Kind:       MismatchMissing,
+			Position:   len(rawArgs) + 1,
+			Identifier: a.identifier,
+			Expected:   a.expected(),
+			command:    c,
+			argument:   a,
+		}
+	}
 
-	if argIndex == len(c.arguments) || argIndex >= len(c.arguments)-c.numOptional {
-		return true, argIndex
+	cmdArg := parsed.positional[m.furthest]
+	mismatch = &MismatchError{Position: parsed.positions[m.furthest] + 1, Arg: cmdArg, command: c}
+	if a == nil {
+		mismatch.Kind = MismatchUnexpected
+		return mismatch
+	}
+
+	mismatch.Kind = MismatchInvalid
+	mismatch.Identifier = a.identifier
+	mismatch.Expected = a.expected()
+	mismatch.Err = a.conversionError(cmdArg)
+	mismatch.argument = a
+	return mismatch
+
+}
+
+// represents determines if this command represents the array of arguments
+func (c *command) represents(rawArgs []string) bool {
+
+	parsed, mismatch := c.parseOptions(rawArgs)
+	if mismatch != nil {
+		return false
+	}
+
+	_, ok := c.match(parsed.positional)
+	return ok
+
+}
+
+// match matches the positional arguments given on the command line to the
+// arguments of this command, returning the index of the argument each of them
+// is matched to, or false if they do not match.
+//
+// Optional groups may appear anywhere. When the arguments could be matched in
+// more than one way, optional groups are matched from left to right: an
+// earlier optional group is given values in preference to a later one, as
+// long as the rest of the arguments still match.
+func (c *command) match(positional []string) ([]int, bool) {
+
+	m := c.matcher(positional)
+	if !m.matchFrom(0, 0) {
+		return nil, false
+	}
+	return m.matched, true
+
+}
+
+// matcher makes a matcher of the positional arguments to the arguments of
+// this command
+func (c *command) matcher(positional []string) *matcher {
+
+	return &matcher{
+		arguments:  c.arguments,
+		optionals:  c.optionals,
+		positional: positional,
+		matched:    make([]int, len(positional)),
+		failed:     make(map[[2]int]bool),
+		furthest:   -1,
+	}
+
+}
+
+// matcher holds the state of a backtracking match of positional arguments to
+// the arguments of a command
+type matcher struct {
+	// arguments contains the arguments of the command
+	arguments []*argument
+
+	// optionals contains the optional groups of the arguments
+	optionals []optionalGroup
+
+	// positional contains the positional arguments given on the command line
+	positional []string
+
+	// matched contains the index of the argument each of the positional
+	// arguments is matched to
+	matched []int
+
+	// failed contains the pairs of argument and positional indexes from which
+	// the rest of the arguments are known not to match, so that they are not
+	// tried again
+	failed map[[2]int]bool
+
+	// furthest is the index of the furthest positional argument a failed
+	// match reached, which is len(positional) if the arguments ran out
+	furthest int
+
+	// expected is the argument that was expected at furthest, or nil if no
+	// more arguments were expected
+	expected *argument
+}
+
+// matchFrom determines if the positional arguments from index i match the
+// arguments from argIndex, recording the index each is matched to
+func (m *matcher) matchFrom(argIndex, i int) bool {
+
+	if argIndex == len(m.arguments) {
+		if i < len(m.positional) {
+			m.fail(i, nil)
+			return false
+		}
+		return true
+	}
+	if m.failed[[2]int{argIndex, i}] {
+		return false
+	}
+
+	a := m.arguments[argIndex]
+
+	if a.isVariable() {
+		// a variable argument takes all of the rest
+		j := i
+		for j < len(m.positional) && a.represents(m.positional[j]) {
+			m.matched[j] = argIndex
+			j++
+		}
+		if j > i && j == len(m.positional) {
+			return true
+		}
+		m.fail(j, a)
+	} else if i < len(m.positional) && a.represents(m.positional[i]) {
+		m.matched[i] = argIndex
+		if m.matchFrom(argIndex+1, i+1) {
+			return true
+		}
+	} else {
+		m.fail(i, a)
+	}
+
+	// leave out the optional groups starting here, the innermost first
+	for _, group := range m.optionals {
+		if group.start == argIndex && m.matchFrom(group.end, i) {
+			return true
+		}
+	}
+
+	m.failed[[2]int{argIndex, i}] = true
+	return false
+
+}
+
+// fail records that the argument a was expected at the positional argument i
+// but was not given, unless a failed match has already reached further
+func (m *matcher) fail(i int, a *argument) {
+
+	if i > m.furthest {
+		m.furthest = i
+		m.expected = a
 	}
 
-	return false, argIndex
 }
 
 func (c *command) isEqualTo(cmd *command) bool {
@@ -131,6 +594,139 @@ func (c *command) isEqualTo(cmd *command) bool {
 
 }
 
+// specificity gets the specificity of the argument each of the positional
+// arguments in rawArgs is matched to, which are compared from left to right to
+// choose between commands that represent the same arguments
+func (c *command) specificity(rawArgs []string) []int {
+
+	parsed, _ := c.parseOptions(rawArgs)
+	matched, _ := c.match(parsed.positional)
+
+	specificity := make([]int, len(matched))
+	for i, argIndex := range matched {
+		specificity[i] = c.arguments[argIndex].specificityOf(parsed.positional[i])
+	}
+	return specificity
+
+}
+
+// isAmbiguousWith determines if some arguments could be represented by both
+// this command and cmd just as specifically, so that neither would be chosen
+// over the other
+func (c *command) isAmbiguousWith(cmd *command) bool {
+
+	return c.overlapsWith(cmd, func(a, b *argument) bool {
+		return a.specificity() == b.specificity() && a.overlaps(b)
+	})
+
+}
+
+// overlapsWith determines if some positional arguments could be matched to the
+// arguments of both this command and cmd, where each is matched to a pair of
+// arguments for which pair returns true
+func (c *command) overlapsWith(cmd *command, pair func(a, b *argument) bool) bool {
+
+	for _, arguments := range c.variants() {
+		for _, other := range cmd.variants() {
+			if len(arguments) > 0 && len(other) > 0 && argumentsOverlap(arguments, other, pair) {
+				return true
+			}
+		}
+	}
+	return false
+
+}
+
+// variants gets the arguments of the command with each combination of the
+// optional groups left out
+func (c *command) variants() [][]*argument {
+
+	return c.variantsFrom(0)
+
+}
+
+// variantsFrom gets the arguments from argIndex with each combination of the
+// optional groups left out
+func (c *command) variantsFrom(argIndex int) [][]*argument {
+
+	if argIndex == len(c.arguments) {
+		return [][]*argument{nil}
+	}
+
+	var variants [][]*argument
+	for _, rest := range c.variantsFrom(argIndex + 1) {
+		variants = append(variants, append([]*argument{c.arguments[argIndex]}, rest...))
+	}
+	for _, group := range c.optionals {
+		if group.start == argIndex {
+			variants = append(variants, c.variantsFrom(group.end)...)
+		}
+	}
+	return variants
+
+}
+
+// argumentsOverlap determines if the same positional arguments could be matched
+// to both a and b, with each matched to a pair of arguments for which pair
+// returns true. A variable argument may be matched to any number of them.
+func argumentsOverlap(a, b []*argument, pair func(a, b *argument) bool) bool {
+
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	if !pair(a[0], b[0]) {
+		return false
+	}
+	return argumentsOverlap(a[1:], b[1:], pair) ||
+		a[0].isVariable() && argumentsOverlap(a, b[1:], pair) ||
+		b[0].isVariable() && argumentsOverlap(a[1:], b, pair)
+
+}
+
+// name gets the literals at the start of the command, such as "user create"
+// for "user create name=(string)"
+func (c *command) name() string {
+
+	var literals []string
+	for _, a := range c.nameArguments() {
+		literals = append(literals, a.literal)
+	}
+	return strings.Join(literals, delimiterArgumentSeparator)
+
+}
+
+// nameArguments gets the literal arguments at the start of the command, which
+// make up its name
+func (c *command) nameArguments() []*argument {
+
+	var name []*argument
+	for _, a := range c.arguments {
+		if !a.isLiteral() || a.isOptional() {
+			break
+		}
+		name = append(name, a)
+	}
+	return name
+
+}
+
+// isNamed determines if the command starts with the literal words, which may
+// be given by their aliases
+func (c *command) isNamed(words []string) bool {
+
+	name := c.nameArguments()
+	if len(words) > len(name) {
+		return false
+	}
+	for i, word := range words {
+		if !name[i].represents(word) {
+			return false
+		}
+	}
+	return true
+
+}
+
 func (c *command) isDefaultCommand() bool {
 	return c.defaultCommand
 }
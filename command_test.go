@@ -4,11 +4,12 @@ import (
 	"github.com/stretchr/objx"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 const (
 	commandString                       = "create kind=project|account name=(string) [description=(string)...]"
-	commandStringOptionalBad            = "create kind=project|account [name=(string)] description=(string)"
+	commandStringOptionalMiddle         = "create kind=project|account [name=(string)] description=(string)"
 	commandStringTwoOptional            = "create kind=project|account name=(string) [description=(string)] [domain=(string)]"
 	commandStringTwoOptionalVariable    = "create kind=project|account name=(string) [description=(string)] [domains=(string)...]"
 	commandStringTwoOptionalVariableBad = "create kind=project|account name=(string) [description=(string)...] [domains=(string)]"
@@ -32,7 +33,7 @@ func HandlerFunc(args objx.Map) {
 
 func TestCommand_makeCommand(t *testing.T) {
 
-	c := makeCommand(commandString, "", "", HandlerFunc)
+	c, _ := makeCommand(commandString, "", "", HandlerFunc)
 
 	if assert.NotNil(t, c) {
 		assert.Equal(t, c.definition, commandString)
@@ -49,35 +50,34 @@ func TestCommand_makeCommand(t *testing.T) {
 		assert.True(t, c.arguments[3].isVariable())
 	}
 
-	assert.Panics(t, func() {
-		_ = makeCommand(commandStringTwoOptionalVariableBad, "", "", HandlerFunc)
-	})
+	assert.Error(t, makeCommandError(commandStringTwoOptionalVariableBad, HandlerFunc))
 
-	assert.Panics(t, func() {
-		_ = makeCommand(commandStringOptionalBad, "", "", HandlerFunc)
-	})
+	assert.NoError(t, makeCommandError(commandStringOptionalMiddle, HandlerFunc))
 
-	assert.Panics(t, func() {
-		_ = makeCommand(commandString, "", "", nil)
-	})
+	assert.Error(t, makeCommandError(commandString, nil))
+
+}
 
+// makeCommandError gets the error making a command with the definition
+func makeCommandError(definition string, handler Handler) error {
+	_, err := makeCommand(definition, "", "", handler)
+	return err
 }
 
 func repBool(c *command, def []string) bool {
-	represents, _ := c.represents(def)
-	return represents
+	return c.represents(def)
 }
 
 func TestCommand_Represents(t *testing.T) {
 
-	c := makeCommand(commandString, "", "", HandlerFunc)
+	c, _ := makeCommand(commandString, "", "", HandlerFunc)
 
 	assert.True(t, repBool(c, rawCommandArrayOne))
 	assert.True(t, repBool(c, rawCommandArrayTwo))
 	assert.True(t, repBool(c, rawCommandArrayThree))
 	assert.True(t, repBool(c, rawCommandArrayFour))
 
-	c = makeCommand(commandStringTwoOptional, "", "", HandlerFunc)
+	c, _ = makeCommand(commandStringTwoOptional, "", "", HandlerFunc)
 
 	assert.True(t, repBool(c, rawCommandArrayOne))
 	assert.True(t, repBool(c, rawCommandArrayTwo))
@@ -85,7 +85,7 @@ func TestCommand_Represents(t *testing.T) {
 	assert.True(t, repBool(c, rawCommandArrayFour))
 	assert.True(t, repBool(c, rawCommandArrayFive))
 
-	c = makeCommand(commandStringTwoOptionalVariable, "", "", HandlerFunc)
+	c, _ = makeCommand(commandStringTwoOptionalVariable, "", "", HandlerFunc)
 
 	assert.True(t, repBool(c, rawCommandArrayOne))
 	assert.True(t, repBool(c, rawCommandArrayTwo))
@@ -96,12 +96,241 @@ func TestCommand_Represents(t *testing.T) {
 
 }
 
+func TestCommand_Match(t *testing.T) {
+
+	tests := []struct {
+		definition string
+		args       []string
+		matched    []int
+		ok         bool
+	}{
+		// required arguments only
+		{"copy src=(string) dst=(string)", []string{"copy", "a", "b"}, []int{0, 1, 2}, true},
+		{"copy src=(string) dst=(string)", []string{"copy", "a"}, nil, false},
+		{"copy src=(string) dst=(string)", []string{"copy", "a", "b", "c"}, nil, false},
+		{"copy src=(string) dst=(string)", []string{"move", "a", "b"}, nil, false},
+
+		// an optional argument in the middle
+		{"copy src=(string) [mode=fast|slow] dst=(string)", []string{"copy", "a", "b"}, []int{0, 1, 3}, true},
+		{"copy src=(string) [mode=fast|slow] dst=(string)", []string{"copy", "a", "fast", "b"}, []int{0, 1, 2, 3}, true},
+		{"copy src=(string) [mode=fast|slow] dst=(string)", []string{"copy", "a", "fast"}, []int{0, 1, 3}, true},
+		{"copy src=(string) [mode=fast|slow] dst=(string)", []string{"copy", "a", "quick", "b"}, nil, false},
+		{"copy src=(string) [mode=fast|slow] dst=(string)", []string{"copy", "a"}, nil, false},
+
+		// optional arguments at the start
+		{"[n=(int)] name=(string)", []string{"5", "five"}, []int{0, 1}, true},
+		{"[n=(int)] name=(string)", []string{"5"}, []int{1}, true},
+		{"[n=(int)] name=(string)", []string{"five"}, []int{1}, true},
+		{"[n=(int)] name=(string)", []string{}, nil, false},
+
+		// multiple optional groups
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "x"}, []int{0, 3}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "1", "x"}, []int{0, 1, 3}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "1", "2", "x"}, []int{0, 1, 2, 3}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "1", "2", "x", "3"}, []int{0, 1, 2, 3, 4}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "x", "3"}, []int{0, 3, 4}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "1", "2", "3", "4"}, []int{0, 1, 2, 3, 4}, true},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "x", "y"}, nil, false},
+		{"tag [a=(int)] [b=(int)] name=(string) [c=(int)]", []string{"tag", "1", "2", "3", "4", "5"}, nil, false},
+
+		// earlier optional arguments are preferred
+		{"pair [a=(int)] [b=(int)] name=(string)", []string{"pair", "1", "x"}, []int{0, 1, 3}, true},
+		{"pair [a=(string)] name=(string) [b=(string)]", []string{"pair", "x", "y"}, []int{0, 1, 2}, true},
+		{"pair [a=(string)] name=(string) [b=(string)]", []string{"pair", "x"}, []int{0, 2}, true},
+
+		// backtracking out of an optional argument
+		{"get [n=(int)] id=(int)", []string{"get", "7"}, []int{0, 2}, true},
+		{"get [n=(int)] id=(int) [label=(string)]", []string{"get", "7", "x"}, []int{0, 2, 3}, true},
+
+		// optional literals and lists
+		{"remove [all] kind=user|group", []string{"remove", "all", "user"}, []int{0, 1, 2}, true},
+		{"remove [all] kind=user|group", []string{"remove", "user"}, []int{0, 2}, true},
+		{"remove [all] kind=user|group", []string{"remove", "all"}, nil, false},
+		{"remove [kind=user|group] [all]", []string{"remove", "all"}, []int{0, 2}, true},
+		{"remove [kind=user|group] [all]", []string{"remove"}, []int{0}, true},
+
+		// variable arguments
+		{"add [force] files=(string)...", []string{"add", "a", "b"}, []int{0, 2, 2}, true},
+		{"add [force] files=(string)...", []string{"add", "force", "a"}, []int{0, 1, 2}, true},
+		{"add [force] files=(string)...", []string{"add", "force"}, []int{0, 2}, true},
+		{"add [force] files=(string)...", []string{"add"}, nil, false},
+		{"add [n=(int)] [files=(string)...]", []string{"add"}, []int{0}, true},
+		{"add [n=(int)] [files=(string)...]", []string{"add", "1", "2"}, []int{0, 1, 2}, true},
+		{"sum nums=(int)...", []string{"sum", "1", "x"}, nil, false},
+
+		// optional groups of more than one argument, and nested groups
+		{"pair [a=(int) b=(int)] name=(string)", []string{"pair", "1", "2", "x"}, []int{0, 1, 2, 3}, true},
+		{"pair [a=(int) b=(int)] name=(string)", []string{"pair", "x"}, []int{0, 3}, true},
+		{"pair [a=(int) b=(int)] name=(string)", []string{"pair", "1", "x"}, nil, false},
+		{"copy src=(string) [dst=(string) [mode=fast|slow]]", []string{"copy", "a"}, []int{0, 1}, true},
+		{"copy src=(string) [dst=(string) [mode=fast|slow]]", []string{"copy", "a", "b"}, []int{0, 1, 2}, true},
+		{"copy src=(string) [dst=(string) [mode=fast|slow]]", []string{"copy", "a", "b", "fast"}, []int{0, 1, 2, 3}, true},
+		{"copy src=(string) [dst=(string) [mode=fast|slow]]", []string{"copy", "a", "b", "quick"}, nil, false},
+
+		// quoted and escaped literals and list items
+		{`say "hello world" [to=(string)]`, []string{"say", "hello world"}, []int{0, 1}, true},
+		{`say "hello world" [to=(string)]`, []string{"say", "hello", "world"}, nil, false},
+		{`pick kind="a b"|a\|b`, []string{"pick", "a|b"}, []int{0, 1}, true},
+		{`pick kind="a b"|a\|b`, []string{"pick", "a b"}, []int{0, 1}, true},
+	}
+
+	for _, test := range tests {
+		c, _ := makeCommand(test.definition, "", "", HandlerFunc)
+		matched, ok := c.match(test.args)
+		assert.Equal(t, ok, test.ok, "%s %v", test.definition, test.args)
+		assert.Equal(t, matched, test.matched, "%s %v", test.definition, test.args)
+	}
+
+}
+
+func TestCommand_OptionalMiddle(t *testing.T) {
+
+	c, _ := makeCommand("copy src=(string) [mode=fast|slow] [--force] dst=(string)", "", "", HandlerFunc)
+
+	assert.Equal(t, c.name(), "copy")
+	assert.True(t, repBool(c, []string{"copy", "a", "b"}))
+	assert.True(t, repBool(c, []string{"copy", "a", "--force", "slow", "b"}))
+
+	args := commandMap(c, []string{"copy", "a", "b"})
+	assert.Equal(t, args["src"], "a")
+	assert.Equal(t, args["dst"], "b")
+	_, ok := args["mode"]
+	assert.False(t, ok)
+
+	args = commandMap(c, []string{"copy", "a", "slow", "b"})
+	assert.Equal(t, args["mode"], "slow")
+	assert.Equal(t, args["dst"], "b")
+
+	c, _ = makeCommand("[all] remove kind=user|group", "", "", HandlerFunc)
+	assert.Equal(t, c.name(), "")
+	assert.True(t, c.arguments[0].isOptional())
+	assert.Equal(t, c.arguments[0].literal, "all")
+
+}
+
+func TestCommand_Options(t *testing.T) {
+
+	c, _ := makeCommand("deploy --force env=(string) --timeout=(int) [--tag|-t=(string)...]", "", "", HandlerFunc)
+
+	if assert.Equal(t, len(c.arguments), 2) && assert.Equal(t, len(c.options), 3) {
+		assert.Equal(t, c.arguments[0].literal, "deploy")
+		assert.Equal(t, c.arguments[1].identifier, "env")
+		assert.Equal(t, c.options[0].identifier, "force")
+		assert.Equal(t, c.options[1].identifier, "timeout")
+		assert.Equal(t, c.options[2].identifier, "tag")
+	}
+
+	assert.True(t, repBool(c, []string{"deploy", "prod"}))
+	assert.True(t, repBool(c, []string{"--force", "deploy", "prod"}))
+	assert.True(t, repBool(c, []string{"deploy", "prod", "--timeout=30", "-t", "a", "--tag", "b"}))
+	assert.True(t, repBool(c, []string{"deploy", "--timeout", "30", "prod"}))
+	assert.True(t, repBool(c, []string{"deploy", "--", "--force"}))
+
+	assert.False(t, repBool(c, []string{"deploy"}))
+	assert.False(t, repBool(c, []string{"deploy", "prod", "--timeout"}))
+	assert.False(t, repBool(c, []string{"deploy", "prod", "--timeout=soon"}))
+	assert.False(t, repBool(c, []string{"deploy", "prod", "--force", "--force"}))
+	assert.False(t, repBool(c, []string{"deploy", "prod", "--force=true"}))
+
+	args := commandMap(c, []string{"deploy", "-t", "a", "prod", "--timeout=30", "--tag=b"})
+	assert.Equal(t, args["env"], "prod")
+	assert.Equal(t, args["force"], false)
+	assert.Equal(t, args["timeout"], int64(30))
+	assert.Equal(t, args["tag"], []string{"a", "b"})
+
+	args = commandMap(c, []string{"deploy", "--force", "--", "--tag"})
+	assert.Equal(t, args["env"], "--tag")
+	assert.Equal(t, args["force"], true)
+	_, ok := args["timeout"]
+	assert.False(t, ok)
+
+	assert.Error(t, makeCommandError("deploy --force --force", HandlerFunc))
+
+	assert.Error(t, makeCommandError("deploy envs=(string)... env=(string)", HandlerFunc))
+
+	assert.NoError(t, makeCommandError("deploy envs=(string)... --force", HandlerFunc))
+
+}
+
+func TestCommand_Defaults(t *testing.T) {
+
+	c, _ := makeCommand("retry name=(string) [retries=(int:0..10)=3] [--delay=(duration)=1s] [--tag=(string)]", "", "", HandlerFunc)
+
+	if assert.Equal(t, len(c.arguments), 3) && assert.Equal(t, len(c.options), 2) {
+		assert.Equal(t, c.arguments[2].identifier, "retries")
+		assert.Equal(t, c.arguments[2].captureType, "int")
+		assert.Equal(t, c.arguments[2].defaultValue, "3")
+		assert.Equal(t, c.options[0].identifier, "delay")
+		assert.Equal(t, c.options[0].defaultValue, "1s")
+		assert.Equal(t, c.options[1].defaultValue, "")
+	}
+
+	args := commandMap(c, []string{"retry", "job"})
+	assert.Equal(t, args["retries"], int64(3))
+	assert.Equal(t, args["delay"], time.Second)
+	_, ok := args["tag"]
+	assert.False(t, ok)
+
+	args = commandMap(c, []string{"retry", "job", "5", "--delay=1m"})
+	assert.Equal(t, args["retries"], int64(5))
+	assert.Equal(t, args["delay"], time.Minute)
+
+	assert.Error(t, makeCommandError("retry retries=(int)=3", HandlerFunc))
+	assert.Error(t, makeCommandError("retry [names=(string)...=all]", HandlerFunc))
+	assert.Error(t, makeCommandError("retry [--tag=(string)=a...]", HandlerFunc))
+
+	commander := New()
+	assert.Panics(t, func() {
+		commander.MustMap("retry [retries=(int)=three]", "", "", HandlerFunc)
+	})
+	assert.Panics(t, func() {
+		commander.MustMap("retry [retries=(int:0..10)=11]", "", "", HandlerFunc)
+	})
+	assert.Panics(t, func() {
+		commander.MustMap("retry [--delay=(duration)=soon]", "", "", HandlerFunc)
+	})
+
+}
+
+func TestCommand_IsAmbiguousWith(t *testing.T) {
+
+	tests := []struct {
+		a, b      string
+		ambiguous bool
+	}{
+		{"show id=(int)", "show name=(string)", false},
+		{"show id=(int)", "show n=(int)", true},
+		{"show id=(int)", "show n=(int:1..10)", true},
+		{"show id=(int)", "show at=(time)", false},
+		{"show kind=user|group", "show type=group|admin", true},
+		{"show kind=user|group", "show type=admin|guest", false},
+		{"show all", "show kind=all|none", false},
+		{"show a=(string)", "show b=(string) c=(string)", false},
+		{"show a=(string) [c=(string)]", "show b=(string)", true},
+		{"show [c=(string)] a=(string)", "show b=(string) d=(int)", false},
+		{"show a=(string)...", "show b=(string) c=(string)", true},
+		{"show a=(int)...", "show b=(int) c=(string)", false},
+		{"[a=(string)]", "[b=(string)]", true},
+		{"", "[b=(string)]", false},
+		{"show [a=(string) b=(string)]", "show c=(string)", false},
+		{"show [a=(string) [b=(string)]]", "show c=(string)", true},
+	}
+
+	for _, test := range tests {
+		a, _ := makeCommand(test.a, "", "", HandlerFunc)
+		b, _ := makeCommand(test.b, "", "", HandlerFunc)
+		assert.Equal(t, a.isAmbiguousWith(b), test.ambiguous, "%s, %s", test.a, test.b)
+		assert.Equal(t, b.isAmbiguousWith(a), test.ambiguous, "%s, %s", test.b, test.a)
+	}
+
+}
+
 func TestCommand_IsEqualTo(t *testing.T) {
 
 	for i := 0; i < len(cmdArray); i++ {
 		for j := 0; j < len(cmdArray); j++ {
-			a := makeCommand(cmdArray[i], "", "", HandlerFunc)
-			a2 := makeCommand(cmdArray[j], "", "", HandlerFunc)
+			a, _ := makeCommand(cmdArray[i], "", "", HandlerFunc)
+			a2, _ := makeCommand(cmdArray[j], "", "", HandlerFunc)
 			if cmdArray[i] == cmdArray[j] {
 				assert.True(t, a.isEqualTo(a2))
 			} else {
@@ -3,6 +3,7 @@ package commander
 import (
 	"fmt"
 	"github.com/stretchr/objx"
+	"io"
 	"os"
 	"path"
 	"strings"
@@ -18,10 +19,23 @@ const DefaultCommand = ""
 
 // Commander provides methods and functionality to create a command line
 // interface quickly and easily.
-type commander struct {
+//
+// Each Commander holds its own set of mapped commands, so a single program
+// may host several independent command sets.
+type Commander struct {
 	// commands contains all the mapped commands
 	commands []*command
 
+	// help is the built-in help command
+	help *command
+
+	// completionCommands contains the built-in commands for shell completion,
+	// which are only mapped if it is enabled with SetCompletion
+	completionCommands []*command
+
+	// groups contains the groups of commands at the top level
+	groups []*Group
+
 	// defaultRegistered stores whether a default has been registered or not
 	defaultRegistered bool
 
@@ -30,182 +44,583 @@ type commander struct {
 
 	// appName stores the name of the currently running application
 	appName string
+
+	// historyFile is the path of the file the console history is kept in
+	historyFile string
+
+	// historySize is the number of lines of console history to keep
+	historySize int
+
+	// interrupted receives the result of the handler the console stopped
+	// waiting for when it was interrupted, or is nil if there is no such
+	// handler still running
+	interrupted chan error
+
+	// stdin is the stream the console reads from
+	stdin io.Reader
+
+	// stdout is the stream usage, help and console output is written to
+	stdout io.Writer
+
+	// stderr is the stream errors and mismatch reports are written to
+	stderr io.Writer
+
+	// diagnostics contains the warnings found when the commands were mapped
+	diagnostics []Diagnostic
+
+	// mapErrors contains the errors returned from Map and MapE, which are
+	// returned from Run
+	mapErrors []error
+
+	// times holds the settings used to convert (time) captures
+	times timeSettings
+
+	// prefixMatching stores whether literals may be given as prefixes
+	prefixMatching bool
+
+	// envPrefix is the prefix of the environment variables arguments are bound
+	// to by Env
+	envPrefix string
+
+	// useConfig stores whether values are taken from config files
+	useConfig bool
+
+	// configPaths contains the paths of the config files, or nil if they are
+	// searched for
+	configPaths []string
+
+	// config contains the values from the config files by section and key, or
+	// nil if they have not been read
+	config map[string]map[string]configValue
 }
 
 // initOnce is used to guarantee that the sharedCommander is initialized only once.
 var initOnce sync.Once
 
-// sharedCommander is the shared instance of the Commander type
-var sharedCommander *commander
+// sharedCommander is the shared instance of the Commander type used by the
+// package level functions
+var sharedCommander *Commander
 
 // incomingArgs is the array of arguments to be analyzed. This exists to facilitate
 // testing.
 var incomingArgs []string
 
-// commandMap builds a map of indentifier,value to be passed to the handler
-func commandMap(cmd *command, args []string) map[string]interface{} {
-	argMap := make(map[string]interface{})
-	for i, a := range cmd.arguments {
-		if len(args) <= i {
-			break
+// New makes a new Commander named after the running application, with the
+// help command already mapped.
+func New() *Commander {
+
+	c := new(Commander)
+
+	c.appName = path.Base(os.Args[0])
+	if extension := path.Ext(os.Args[0]); extension != "" {
+		c.appName = strings.Replace(c.appName, extension, "", 1)
+	}
+
+	c.envPrefix = envName(c.appName)
+	c.historyFile = defaultHistoryFile(c.appName)
+	c.historySize = defaultHistorySize
+
+	c.stdin = os.Stdin
+	c.stdout = os.Stdout
+	c.stderr = os.Stderr
+
+	c.MustMap("help [command=(string)...]", "Prints help and usage",
+		"Prints help and usage for the commands. \"help <command>\" will print additional information about the command, and \"help <group>\" will list the commands in the group.",
+		func(args objx.Map) {
+			words, _ := args["command"].([]string)
+			c.printHelp(words)
+		})
+	c.help = c.commands[len(c.commands)-1]
+
+	return c
+
+}
+
+// printUsage prints the usage of the program to w
+func (c *Commander) printUsage(w io.Writer, cmd *command) {
+
+	if cmd == nil {
+		if !c.interactive {
+			fmt.Fprintf(w, "\nusage: %s <command> [arguments]\n\n", c.appName)
 		}
+		c.printCommands(w, nil)
+	} else {
+		fmt.Fprintf(w, "\n\"%s\" usage:\n\n", cmd.name())
+		fmt.Fprintf(w, "    %s - %s\n", cmd.title(), cmd.summary)
+		fmt.Fprintf(w, "    %s\n", cmd.description)
+		c.printArguments(w, cmd)
+	}
+	fmt.Fprintln(w)
+
+}
+
+// printArguments prints a line describing what each of the arguments and
+// options of the command that take a value expects
+func (c *Commander) printArguments(w io.Writer, cmd *command) {
+
+	var lines []string
+	for _, a := range cmd.arguments {
 		if !a.isLiteral() {
-			if !a.isVariable() {
-				argMap[a.identifier] = args[i]
-			} else {
-				if len(cmd.arguments) == len(args) {
-					argMap[a.identifier] = args[i]
-				} else {
-					argMap[a.identifier] = args[i:]
-				}
+			lines = append(lines, fmt.Sprintf("    %s - %s%s\n", a.identifier, a.expected(), describeFallbacks(cmd.envVar(a.identifier), a.defaultValue)))
+		}
+		for _, item := range a.list {
+			if help, ok := a.listHelp[item]; ok {
+				lines = append(lines, fmt.Sprintf("        %s - %s\n", item, help))
 			}
 		}
 	}
-	return argMap
+	for _, o := range cmd.options {
+		if !o.isSwitch() || cmd.envVar(o.identifier) != "" {
+			lines = append(lines, fmt.Sprintf("    %s - %s%s\n", strings.Join(o.names(), delimiterListItems), o.expected(), describeFallbacks(cmd.envVar(o.identifier), o.defaultValue)))
+		}
+	}
+
+	if len(lines) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, strings.Join(lines, ""))
+	}
+
 }
 
-// printUsage prints the usage of the program
-func printUsage(cmd *command) {
+// printHelp prints the help for the words given to the help command. The
+// words may name a group, whose commands are listed, or the start of one
+// or more commands, whose usage is printed. Otherwise the usage of the
+// program is printed.
+func (c *Commander) printHelp(words []string) {
 
-	if cmd == nil {
-		if !sharedCommander.interactive {
-			fmt.Printf("\nusage: %s <command> [arguments]\n\n", sharedCommander.appName)
-		}
-		for _, cmd := range sharedCommander.commands {
-			if !cmd.isDefaultCommand() {
-				fmt.Printf("    %s - %s\n", cmd.definition, cmd.summary)
-			}
+	if len(words) == 0 {
+		c.printUsage(c.stdout, nil)
+		return
+	}
+
+	if group := c.findGroup(words); group != nil {
+		c.printGroupUsage(c.stdout, group)
+		return
+	}
+
+	printed := false
+	for _, cmd := range c.commands {
+		if !cmd.hidden && cmd.isNamed(words) {
+			c.printUsage(c.stdout, cmd)
+			printed = true
 		}
-	} else {
-		fmt.Printf("\n\"%s\" usage:\n\n", cmd.arguments[0].literal)
-		fmt.Printf("    %s - %s\n", cmd.definition, cmd.summary)
-		fmt.Printf("    %s\n", cmd.description)
 	}
-	fmt.Println()
+
+	if !printed {
+		c.printUsage(c.stdout, nil)
+	}
 
 }
 
-// moveHelpToEnd moves the help entry to the end of the array for printing
-func moveHelpToEnd() {
-	length := len(sharedCommander.commands)
+// describeFallbacks describes the environment variable and default value an
+// argument or option falls back to, if any, to follow the description of what
+// it expects
+func describeFallbacks(env, defaultValue string) string {
+
+	var fallbacks []string
+	if env != "" {
+		fallbacks = append(fallbacks, "env "+env)
+	}
+	if defaultValue != "" {
+		fallbacks = append(fallbacks, "default "+defaultValue)
+	}
 
-	for i := 0; i < length-1; i++ {
-		sharedCommander.commands[i], sharedCommander.commands[i+1] = sharedCommander.commands[i+1], sharedCommander.commands[i]
+	if len(fallbacks) == 0 {
+		return ""
 	}
+	return " (" + strings.Join(fallbacks, ", ") + ")"
+
+}
+
+// SetStdin sets the stream the interactive console reads from. The default
+// is os.Stdin.
+func (c *Commander) SetStdin(stdin io.Reader) {
+	c.stdin = stdin
 }
 
-// initialize sets up various internal fields to ready the system. If this is not
-// called, Commander will not function.
+// SetStdout sets the stream usage, help and the interactive console are
+// written to. The default is os.Stdout.
+func (c *Commander) SetStdout(stdout io.Writer) {
+	c.stdout = stdout
+}
+
+// SetStderr sets the stream errors, and the usage shown when the arguments
+// do not match a command, are written to. The default is os.Stderr.
+func (c *Commander) SetStderr(stderr io.Writer) {
+	c.stderr = stderr
+}
+
+// moveHelpToEnd moves the help entry to the end of the array for printing,
+// after the completion command if it is mapped, so that the built-in commands
+// are listed after those of the program
+func (c *Commander) moveHelpToEnd() {
+	for _, builtIn := range append(c.completionCommands[:len(c.completionCommands):len(c.completionCommands)], c.help) {
+		for i, cmd := range c.commands {
+			if cmd == builtIn {
+				c.commands = append(append(c.commands[:i:i], c.commands[i+1:]...), cmd)
+				break
+			}
+		}
+	}
+}
+
+// initialize sets up the sharedCommander used by the package level functions.
+// If this is not called, the package level functions will not function.
 func initialize() {
 	initOnce.Do(func() {
-		sharedCommander = new(commander)
-
-		sharedCommander.appName = path.Base(os.Args[0])
-		if extension := path.Ext(os.Args[0]); extension != "" {
-			sharedCommander.appName = strings.Replace(sharedCommander.appName, extension, "", 1)
-		}
-
-		Map("help [arg=(string)]", "Prints help and usage",
-			"Prints help and usage for the commands. \"help <command>\" will print additional information about the command.",
-			func(args objx.Map) {
-				printed := false
-				if len(args) == 1 {
-					for _, cmd := range sharedCommander.commands {
-						if cmd.arguments[0].literal == args["arg"].(string) {
-							printUsage(cmd)
-							printed = true
-						}
-					}
-				}
-				if !printed {
-					printUsage(nil)
-				}
-			})
+		sharedCommander = New()
 	})
 }
 
-// execute fires up the commander system, either launching the interactive
+// execute fires up the sharedCommander, either launching the interactive
 // console, or executing the command provided by the arguments
-func execute() {
+func execute() error {
 
-	moveHelpToEnd()
+	if incomingArgs == nil {
+		incomingArgs = os.Args[1:]
+	}
 
-	if sharedCommander.interactive && len(os.Args) == 1 {
-		launchConsole()
-		return
+	return sharedCommander.Run(incomingArgs)
+
+}
+
+// Run fires up the commander, either launching the interactive console (if
+// it is interactive and no arguments are given), or executing the command
+// provided by the arguments.
+//
+// The args should not include the program name, for example os.Args[1:].
+//
+// Run returns the error returned by the handler, ErrUsage if the arguments
+// do not match any of the mapped commands, the error reading the config
+// files if they are used and cannot be read, or the first error returned from
+// Map or MapE, in which case nothing is run.
+func (c *Commander) Run(args []string) error {
+
+	if len(c.mapErrors) > 0 {
+		return c.mapErrors[0]
 	}
 
-	if incomingArgs == nil {
-		incomingArgs = os.Args[1:]
+	c.moveHelpToEnd()
+
+	if err := c.loadConfig(); err != nil {
+		return err
+	}
+
+	if c.interactive && len(args) == 0 {
+		c.launchConsole()
+		return nil
 	}
 
 	// handle the arguments passed during program invocation
-	handleInvocation(incomingArgs)
+	return c.handleInvocation(args)
 
 }
 
 // handleInvocation analyzes the arguments and executes the
 // appropriate command handler function
-func handleInvocation(args []string) {
+func (c *Commander) handleInvocation(args []string) error {
 
+	var handlerErr error
 	executed := false
-	closestMatchCount := 0
-	var closestMatch *command
 
 	executeDefault := len(args) == 0
 
 	if executeDefault {
-		for _, cmd := range sharedCommander.commands {
+		for _, cmd := range c.commands {
 			if cmd.isDefaultCommand() {
-				cmd.handler(nil)
+				handlerErr = cmd.handler(nil)
 				executed = true
 			}
 		}
-	} else {
-		for _, cmd := range sharedCommander.commands {
-			if represents, matchCount := cmd.represents(args); represents {
-				argMap := commandMap(cmd, args)
-				cmd.handler(argMap)
-				executed = true
-			} else {
-				if matchCount > closestMatchCount {
-					closestMatchCount = matchCount
-					closestMatch = cmd
-				}
-			}
+	} else if cmd := c.commandFor(args); cmd != nil {
+		if mismatch := c.ambiguousPrefix(cmd, args); mismatch != nil {
+			fmt.Fprintf(c.stderr, "\n%s\n", mismatch)
+			c.printUsage(c.stderr, nil)
+			return mismatch
+		}
+		argMap, mismatch := resolveArgs(cmd, args)
+		if mismatch != nil {
+			mismatch.Definition = cmd.definition
+			mismatch.command = cmd
+			fmt.Fprintf(c.stderr, "\n%s\n", mismatch)
+			c.printUsage(c.stderr, cmd)
+			return mismatch
 		}
+		handlerErr = cmd.handler(argMap)
+		executed = true
 	}
 	if !executed {
-		printUsage(closestMatch)
+		mismatch := c.closestMismatch(args)
+		fmt.Fprintf(c.stderr, "\n%s\n", mismatch)
+		if mismatch.group != nil {
+			c.printGroupUsage(c.stderr, mismatch.group)
+		} else {
+			c.printUsage(c.stderr, mismatch.command)
+		}
+		return mismatch
 	}
 
+	return handlerErr
+
+}
+
+// commandFor gets the command the arguments represent, or nil if they do not
+// represent any. When they represent more than one command, the most specific
+// is chosen by comparing the arguments each positional argument is matched to,
+// from left to right: literals are chosen over lists, lists over captures of
+// other types, those over string captures, and string captures over literals
+// given as prefixes (see SetPrefixMatching). If the commands are just as
+// specific, the one that was mapped first is chosen.
+func (c *Commander) commandFor(args []string) *command {
+
+	return mostSpecific(c.commands, args)
+
+}
+
+// mostSpecific gets the most specific of the commands that represent the
+// arguments, or nil if none of them do.
+//
+// See Commander.commandFor.
+func mostSpecific(commands []*command, args []string) *command {
+
+	var chosen *command
+	var chosenSpecificity []int
+	for _, cmd := range commands {
+		if !cmd.represents(args) {
+			continue
+		}
+		specificity := cmd.specificity(args)
+		if chosen == nil || compareSpecificity(specificity, chosenSpecificity) > 0 {
+			chosen, chosenSpecificity = cmd, specificity
+		}
+	}
+	return chosen
+
+}
+
+// compareSpecificity compares the specificity of the arguments two commands
+// matched, returning a positive number if a is more specific, a negative
+// number if b is, or zero if they are just as specific
+func compareSpecificity(a, b []int) int {
+
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+
+}
+
+// closestMismatch explains why the arguments do not match any of the commands,
+// using the command that matched furthest into the arguments before failing.
+// If no command matched beyond the first argument, the mismatch is reported as
+// an unknown command. If several commands expected different literals at that
+// point, such as the commands in a group, they are all listed as expected.
+func (c *Commander) closestMismatch(args []string) *MismatchError {
+
+	var closest []*MismatchError
+	for _, cmd := range c.commands {
+		if cmd.hidden || cmd.isDefaultCommand() {
+			continue
+		}
+		if mismatch := cmd.mismatch(args); mismatch != nil {
+			switch {
+			case len(closest) == 0 || mismatch.Position > closest[0].Position:
+				closest = []*MismatchError{mismatch}
+			case mismatch.Position == closest[0].Position:
+				closest = append(closest, mismatch)
+			}
+		}
+	}
+
+	if len(closest) == 0 || closest[0].Position <= 1 {
+		unknown := &MismatchError{Kind: MismatchUnknown, Position: 1}
+		if len(args) > 0 {
+			unknown.Arg = args[0]
+		}
+		return unknown
+	}
+
+	mismatch := closest[0]
+
+	// list all the literals expected at the position, and use the usage of
+	// the group they belong to
+	var literals []string
+	for _, other := range closest {
+		if a := other.argument; a != nil && a.isLiteral() && !containsString(literals, a.literal) {
+			literals = append(literals, a.literal)
+			if len(literals) == 1 {
+				mismatch = other
+			}
+		}
+	}
+	if len(literals) > 1 {
+		mismatch.Expected = "one of " + strings.Join(literals, delimiterListItems)
+		mismatch.Definition = ""
+		mismatch.group = c.findGroup(args[:mismatch.Position-1])
+		mismatch.command = nil
+	} else {
+		mismatch.Definition = mismatch.command.definition
+	}
+
+	return mismatch
+
 }
 
 // Map is used to map a definition string to a handler function. If the arguments
 // given on the command line are represented by the definition string, the
 // handler function will be called.
-func Map(definition, summary, description string, handler Handler) {
+//
+// The opts change how the command is matched or its arguments are converted,
+// for example TimeLayouts.
+//
+// Map returns a *DefinitionError if the definition is not valid, or conflicts
+// with a command already mapped (see Analyze), in which case the command is
+// not mapped. The error is also kept, and returned from Run instead of running
+// anything, so that it is not lost if it is not checked.
+func (c *Commander) Map(definition, summary, description string, handler Handler, opts ...MapOption) error {
+
+	return c.keepError(c.mapHandler(definition, summary, description, handler, opts))
+
+}
+
+// MapE is used to map a definition string to a handler function that can fail.
+// The error returned by the handler is returned from Run.
+//
+// See Map.
+func (c *Commander) MapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) error {
+
+	return c.keepError(c.mapErrorHandler(definition, summary, description, handler, opts))
+
+}
+
+// MustMap is like Map, but panics if the command cannot be mapped.
+func (c *Commander) MustMap(definition, summary, description string, handler Handler, opts ...MapOption) {
+
+	if err := c.mapHandler(definition, summary, description, handler, opts); err != nil {
+		panic(err)
+	}
+
+}
+
+// MustMapE is like MapE, but panics if the command cannot be mapped.
+func (c *Commander) MustMapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) {
+
+	if err := c.mapErrorHandler(definition, summary, description, handler, opts); err != nil {
+		panic(err)
+	}
+
+}
+
+// mapHandler makes a command with the handler and maps it
+func (c *Commander) mapHandler(definition, summary, description string, handler Handler, opts []MapOption) error {
+
+	cmd, err := makeCommand(definition, summary, description, handler, opts...)
+	if err != nil {
+		return err
+	}
+	return c.mapCommand(cmd)
+
+}
+
+// mapErrorHandler makes a command with the handler that can fail and maps it
+func (c *Commander) mapErrorHandler(definition, summary, description string, handler ErrorHandler, opts []MapOption) error {
+
+	cmd, err := makeCommandE(definition, summary, description, handler, opts...)
+	if err != nil {
+		return err
+	}
+	return c.mapCommand(cmd)
+
+}
+
+// keepError keeps the error from mapping a command, if it is not nil, so that
+// it can be returned from Run
+func (c *Commander) keepError(err error) error {
+
+	if err != nil {
+		c.mapErrors = append(c.mapErrors, err)
+	}
+	return err
+
+}
+
+// mapCommand adds a command to the commander, returning a *DefinitionError if
+// it clashes with the commands already mapped
+func (c *Commander) mapCommand(newCommand *command) error {
+
+	if newCommand.isDefaultCommand() && c.defaultRegistered {
+		return &DefinitionError{Definition: newCommand.definition, Message: "only one default command can be mapped"}
+	}
+
+	newCommand.commander = c
+	diagnostics := c.analyze(newCommand)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Severity == SeverityError {
+			return diagnostic.err()
+		}
+	}
+	c.diagnostics = append(c.diagnostics, diagnostics...)
+
+	if newCommand.isDefaultCommand() {
+		c.defaultRegistered = true
+	}
+	c.commands = append(c.commands, newCommand)
+	return nil
+
+}
+
+// Map is used to map a definition string to a handler function on the shared
+// Commander used by Go.
+//
+// See Commander.Map.
+func Map(definition, summary, description string, handler Handler, opts ...MapOption) error {
 
 	if sharedCommander == nil {
 		panic("Initialize must be called before Map")
 	}
 
-	if definition == DefaultCommand {
-		if sharedCommander.defaultRegistered {
-			panic("Only one default command can be registered.")
-		} else {
-			sharedCommander.defaultRegistered = true
-		}
+	return sharedCommander.Map(definition, summary, description, handler, opts...)
+
+}
+
+// MapE is used to map a definition string to a handler function that can fail
+// on the shared Commander used by Go.
+//
+// See Commander.MapE.
+func MapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) error {
+
+	if sharedCommander == nil {
+		panic("Initialize must be called before Map")
 	}
 
-	newCommand := makeCommand(definition, summary, description, handler)
+	return sharedCommander.MapE(definition, summary, description, handler, opts...)
 
-	for _, cmd := range sharedCommander.commands {
-		if cmd.isEqualTo(newCommand) {
-			panic("Each command must have a unique signature.")
-		}
+}
+
+// MustMap is like Map, but panics if the command cannot be mapped.
+//
+// See Commander.MustMap.
+func MustMap(definition, summary, description string, handler Handler, opts ...MapOption) {
+
+	if sharedCommander == nil {
+		panic("Initialize must be called before Map")
+	}
+
+	sharedCommander.MustMap(definition, summary, description, handler, opts...)
+
+}
+
+// MustMapE is like MapE, but panics if the command cannot be mapped.
+//
+// See Commander.MustMapE.
+func MustMapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) {
+
+	if sharedCommander == nil {
+		panic("Initialize must be called before Map")
 	}
 
-	sharedCommander.commands = append(sharedCommander.commands, newCommand)
+	sharedCommander.MustMapE(definition, summary, description, handler, opts...)
 
 }
@@ -1,71 +1,156 @@
 package commander
 
 import (
+	"bytes"
+	"errors"
 	"github.com/stretchr/objx"
 	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
+	"time"
 )
 
+func TestCommander_New(t *testing.T) {
+
+	c := New()
+
+	if assert.NotNil(t, c) {
+		assert.NotEmpty(t, c.appName)
+		if assert.Equal(t, len(c.commands), 1) {
+			assert.Equal(t, c.commands[0], c.help)
+		}
+	}
+
+}
+
 func TestCommander_Map(t *testing.T) {
 
-	sharedCommander = new(commander)
+	c := New()
+	builtIn := len(c.commands)
 
-	Map(commandString, "", "", func(objx.Map) {
+	c.Map(commandString, "", "", func(objx.Map) {
 	})
 
-	assert.Equal(t, len(sharedCommander.commands), 1)
+	assert.Equal(t, len(c.commands), builtIn+1)
 
-	Map(DefaultCommand, "", "", func(objx.Map) {
+	c.Map(DefaultCommand, "", "", func(objx.Map) {
 	})
 
-	assert.Equal(t, len(sharedCommander.commands), 2)
+	assert.Equal(t, len(c.commands), builtIn+2)
 
 	assert.Panics(t, func() {
-		Map(DefaultCommand, "", "", func(objx.Map) {
+		c.MustMap(DefaultCommand, "", "", func(objx.Map) {
 		})
 	})
 
 	assert.Panics(t, func() {
-		Map(commandString, "", "", func(objx.Map) {
+		c.MustMap(commandString, "", "", func(objx.Map) {
+		})
+	})
+
+}
+
+func TestCommander_Independent(t *testing.T) {
+
+	c1 := New()
+	c2 := New()
+
+	called1, called2 := false, false
+
+	c1.Map("create", "", "", func(objx.Map) {
+		called1 = true
+	})
+	c2.Map("create", "", "", func(objx.Map) {
+		called2 = true
+	})
+
+	c1.Run([]string{"create"})
+	assert.True(t, called1)
+	assert.False(t, called2)
+
+	c2.Run([]string{"create"})
+	assert.True(t, called2)
+
+}
+
+func TestCommander_Parallel(t *testing.T) {
+
+	// commanders share no state, so they can be used from parallel tests
+	for _, name := range []string{"alpha", "beta", "gamma", "delta"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := New()
+			stdout := new(bytes.Buffer)
+			c.SetStdout(stdout)
+
+			var got string
+			c.Map("greet name=(string)", "Greets "+name, "", func(args objx.Map) {
+				got = args["name"].(string)
+			})
+
+			assert.NoError(t, c.Run([]string{"greet", name}))
+			assert.Equal(t, got, name)
+			assert.NoError(t, c.Run([]string{"help"}))
+			assert.Contains(t, stdout.String(), "Greets "+name)
 		})
+	}
+
+}
+
+func TestCommander_moveHelpToEnd(t *testing.T) {
+
+	c := New()
+	builtIn := len(c.commands)
+
+	c.Map("create", "", "", func(objx.Map) {
 	})
+	c.Map("delete", "", "", func(objx.Map) {
+	})
+
+	c.moveHelpToEnd()
+	c.moveHelpToEnd()
+
+	if assert.Equal(t, len(c.commands), builtIn+2) {
+		assert.Equal(t, c.commands[builtIn-1].definition, "create")
+		assert.Equal(t, c.commands[builtIn].definition, "delete")
+		assert.Equal(t, c.commands[builtIn+1], c.help)
+	}
 
 }
 
-func TestCommander_execute(t *testing.T) {
+func TestCommander_Run(t *testing.T) {
 
-	sharedCommander = new(commander)
-	incomingArgs = []string{}
+	c := New()
 
 	called := false
 
-	Map(DefaultCommand, "", "", func(args objx.Map) {
+	c.Map(DefaultCommand, "", "", func(args objx.Map) {
 		called = true
 	})
 
-	execute()
+	c.Run([]string{})
 	assert.True(t, called)
 
 	called = false
-	sharedCommander = new(commander)
+	c = New()
 
-	Map(commandString, "", "", func(args objx.Map) {
+	c.Map(commandString, "", "", func(args objx.Map) {
 		called = true
 		assert.Equal(t, len(args), 3)
 		assert.Equal(t, args["kind"], "account")
 		assert.Equal(t, args["name"], "mat")
-		assert.Equal(t, args["description"], "Crazy Brit!")
+		assert.Equal(t, args["description"], []string{"Crazy Brit!"})
 	})
 
-	incomingArgs = rawCommandArrayFour
-
-	execute()
+	c.Run(rawCommandArrayFour)
 	assert.True(t, called)
 
 	called = false
-	sharedCommander = new(commander)
+	c = New()
 
-	Map(commandStringTwoOptionalVariable, "", "", func(args objx.Map) {
+	c.Map(commandStringTwoOptionalVariable, "", "", func(args objx.Map) {
 		called = true
 
 		assert.Equal(t, len(args), 4)
@@ -80,62 +165,246 @@ func TestCommander_execute(t *testing.T) {
 		}
 	})
 
-	incomingArgs = rawCommandArraySix
-
-	execute()
+	c.Run(rawCommandArraySix)
 	assert.True(t, called)
 
 	called = false
 }
 
-func TestCommander_NoOptional(t *testing.T) {
+func TestCommander_RunErrors(t *testing.T) {
 
-	sharedCommander = new(commander)
+	c := New()
 
-	Map(commandStringTwoOptionalVariable, "", "", func(args objx.Map) {
+	failure := errors.New("failed")
+
+	c.MapE("fail", "", "", func(args objx.Map) error {
+		return failure
+	})
+	c.MapE("succeed", "", "", func(args objx.Map) error {
+		return nil
 	})
 
-	incomingArgs = rawCommandArraySeven
+	assert.Equal(t, c.Run([]string{"fail"}), failure)
+	assert.Nil(t, c.Run([]string{"succeed"}))
+	assert.True(t, errors.Is(c.Run([]string{"unknown"}), ErrUsage))
+	assert.True(t, errors.Is(c.Run([]string{}), ErrUsage))
+	assert.Nil(t, c.Run([]string{"help"}))
+
+	assert.Panics(t, func() {
+		c.MustMapE("nil", "", "", nil)
+	})
+
+}
+
+func TestCommander_Mismatch(t *testing.T) {
+
+	c := New()
 
+	c.Map(commandString, "", "", func(objx.Map) {
+	})
+	c.Map("add num=(int) --timeout=(int) --force", "", "", func(objx.Map) {
+	})
+	c.Map("range [from=(int) to=(int)] name=(string)", "", "", func(objx.Map) {
+	})
+
+	for _, test := range []struct {
+		args       []string
+		kind       MismatchKind
+		definition string
+		position   int
+		message    string
+	}{
+		{[]string{}, MismatchUnknown, "", 1, "no command given"},
+		{[]string{"unknown"}, MismatchUnknown, "", 1, "unknown command 'unknown'"},
+		{[]string{"create", "thing"}, MismatchInvalid, commandString, 2, "argument 2 'thing' is not one of project|account for kind"},
+		{[]string{"create", "project"}, MismatchMissing, commandString, 3, "argument 3 is missing, expected a string for name"},
+		{[]string{"add", "abc"}, MismatchInvalid, "add num=(int) --timeout=(int) --force", 2, "argument 2 'abc' is not a valid int for num: not a whole number"},
+		{[]string{"add", "1", "2"}, MismatchUnexpected, "add num=(int) --timeout=(int) --force", 3, "argument 3 '2' is unexpected"},
+		{[]string{"add", "1", "--timeout=soon"}, MismatchInvalid, "add num=(int) --timeout=(int) --force", 3, "option --timeout 'soon' is not a valid int for timeout: not a whole number"},
+		{[]string{"add", "1", "--timeout"}, MismatchMissing, "add num=(int) --timeout=(int) --force", 3, "option --timeout is missing, expected a valid int for timeout"},
+		{[]string{"add", "1", "--force", "--force"}, MismatchUnexpected, "add num=(int) --timeout=(int) --force", 4, "option --force may only be given once"},
+		{[]string{"add", "1", "--force=yes"}, MismatchUnexpected, "add num=(int) --timeout=(int) --force", 3, "option --force does not take a value, but was given 'yes'"},
+		{[]string{"range", "1", "foo"}, MismatchInvalid, "range [from=(int) to=(int)] name=(string)", 3, "argument 3 'foo' is not a valid int for to: not a whole number"},
+		{[]string{"range", "1", "2"}, MismatchMissing, "range [from=(int) to=(int)] name=(string)", 4, "argument 4 is missing, expected a string for name"},
+		{[]string{"range", "a", "b", "c"}, MismatchUnexpected, "range [from=(int) to=(int)] name=(string)", 3, "argument 3 'b' is unexpected"},
+		{[]string{"range"}, MismatchMissing, "range [from=(int) to=(int)] name=(string)", 2, "argument 2 is missing, expected a valid int for from"},
+	} {
+		err := c.Run(test.args)
+		if mismatch, ok := err.(*MismatchError); assert.True(t, ok, "%v", test.args) {
+			assert.Equal(t, mismatch.Kind, test.kind, "%v", test.args)
+			assert.Equal(t, mismatch.Definition, test.definition, "%v", test.args)
+			assert.Equal(t, mismatch.Position, test.position, "%v", test.args)
+			assert.Equal(t, mismatch.Error(), test.message, "%v", test.args)
+			assert.True(t, errors.Is(err, ErrUsage))
+		}
+	}
+
+}
+
+func TestCommander_Streams(t *testing.T) {
+
+	c := New()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+
+	c.Map(commandString, "Creates something", "Creates a thing of the specified kind.", func(objx.Map) {
+	})
+
+	assert.NoError(t, c.Run([]string{"help"}))
+	assert.Contains(t, stdout.String(), "usage: ")
+	assert.Contains(t, stdout.String(), commandString+" - Creates something")
+	assert.Empty(t, stderr.String())
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help", "create"}))
+	assert.Contains(t, stdout.String(), "Creates a thing of the specified kind.")
+
+	stdout.Reset()
+	assert.Error(t, c.Run([]string{"create", "thing"}))
+	assert.Empty(t, stdout.String())
+	assert.Contains(t, stderr.String(), "argument 2 'thing' is not one of project|account for kind")
+	assert.Contains(t, stderr.String(), "\"create\" usage:")
+
+}
+
+func TestCommander_TypedArguments(t *testing.T) {
+
+	c := New()
+
+	called := false
+
+	c.Map("add num=(int) [nums=(uint)...]", "", "", func(args objx.Map) {
+		called = true
+		assert.Equal(t, args["num"], int64(-1))
+		assert.Equal(t, args["nums"], []uint64{2, 3})
+	})
+
+	c.Run([]string{"add", "-1", "2", "3"})
+	assert.True(t, called)
+
+}
+
+func TestCommander_Defaults(t *testing.T) {
+
+	c := New()
+	stdout := new(bytes.Buffer)
+	c.SetStdout(stdout)
+
+	var args objx.Map
+	c.Map("fetch url=(string) [retries=(int)=3] [--timeout=(duration)=30s]", "Fetches", "Fetches a url.", func(a objx.Map) {
+		args = a
+	})
+
+	if assert.NoError(t, c.Run([]string{"fetch", "example.com"})) {
+		assert.Equal(t, args["retries"], int64(3))
+		assert.Equal(t, args["timeout"], 30*time.Second)
+	}
+
+	assert.NoError(t, c.Run([]string{"help", "fetch"}))
+	assert.Contains(t, stdout.String(), "fetch url=(string) [retries=(int)=3] [--timeout=(duration)=30s] - Fetches")
+	assert.Contains(t, stdout.String(), "    retries - a valid int (default 3)\n")
+	assert.Contains(t, stdout.String(), "    --timeout - a valid duration (default 30s)\n")
+
+}
+
+func TestCommander_Specificity(t *testing.T) {
+
+	c := New()
+
+	var ran []string
+	mapRan := func(definition string) {
+		c.Map(definition, "", "", func(objx.Map) {
+			ran = append(ran, definition)
+		})
+	}
+	mapRan("show name=(string)")
+	mapRan("show id=(int)")
+	mapRan("show kind=user|group")
+	mapRan("show all")
+	mapRan("show name=(string) detail=(string)")
+	mapRan("show id=(int) detail=(string)")
+
+	tests := map[string]string{
+		"show mat":      "show name=(string)",
+		"show 42":       "show id=(int)",
+		"show user":     "show kind=user|group",
+		"show all":      "show all",
+		"show mat full": "show name=(string) detail=(string)",
+		"show 42 full":  "show id=(int) detail=(string)",
+	}
+	for args, expected := range tests {
+		ran = nil
+		if assert.NoError(t, c.Run(strings.Split(args, " ")), args) {
+			assert.Equal(t, ran, []string{expected}, args)
+		}
+	}
+
+	// the same arguments are always matched just as specifically
+	assert.Panics(t, func() {
+		c.MustMap("show other=(string)", "", "", HandlerFunc)
+	})
+	assert.Panics(t, func() {
+		c.MustMap("show [type=user|admin]", "", "", HandlerFunc)
+	})
+	assert.Panics(t, func() {
+		c.MustMap("show name=(string) more=(string)...", "", "", HandlerFunc)
+	})
+
+	// these can never represent the same arguments as a mapped command
 	assert.NotPanics(t, func() {
-		execute()
+		c.Map("show at=(time)", "", "", HandlerFunc)
+		c.Map("show kind=admin|guest", "", "", HandlerFunc)
+		c.Map("show 42", "", "", HandlerFunc)
+	})
+
+}
+
+func TestCommander_NoOptional(t *testing.T) {
+
+	c := New()
+
+	c.Map(commandStringTwoOptionalVariable, "", "", func(args objx.Map) {
+	})
+
+	assert.NotPanics(t, func() {
+		c.Run(rawCommandArraySeven)
 	})
 
 }
 
 func TestCommander_Real(t *testing.T) {
 
-	initialize()
+	c := New()
 
-	Map(DefaultCommand, "", "", func(args objx.Map) {
+	c.Map(DefaultCommand, "", "", func(args objx.Map) {
 	})
 
-	Map("test [name=(string)]", "", "",
+	c.Map("test [name=(string)]", "", "",
 		func(args objx.Map) {
 		})
 
-	Map("install [name=(string)]", "", "",
+	c.Map("install [name=(string)]", "", "",
 		func(args objx.Map) {
 		})
 
-	Map("vet [name=(string)]", "", "",
+	c.Map("vet [name=(string)]", "", "",
 		func(args objx.Map) {
 		})
 
-	Map("exclude name=(string)", "", "",
+	c.Map("exclude name=(string)", "", "",
 		func(args objx.Map) {
 		})
 
-	Map("include name=(string)", "", "",
+	c.Map("include name=(string)", "", "",
 		func(args objx.Map) {
 		})
 
-	Map("exclusions", "", "",
+	c.Map("exclusions", "", "",
 		func(args objx.Map) {
 		})
 
-	incomingArgs = []string{"test"}
-	execute()
+	c.Run([]string{"test"})
 
 }
 
@@ -0,0 +1,269 @@
+package commander
+
+import (
+	"fmt"
+	"github.com/stretchr/objx"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// completionDefinition is the definition of the built-in command that prints
+	// shell completion scripts
+	completionDefinition string = "completion shell=bash|zsh|fish"
+
+	// completeLiteral is the literal of the hidden built-in command the shell
+	// completion scripts call to get the candidates for the current word
+	completeLiteral string = "__complete"
+)
+
+// completionScripts holds the shell completion script templates by shell. In
+// each, %[1]s is a name safe to use in shell function names, and %[2]s is the
+// name of the application.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for %[2]s
+_%[1]s_complete() {
+    local IFS=$'\n' i
+    local -a words=()
+    # bash splits --name=value into three words at the = (see COMP_WORDBREAKS),
+    # so join them back into one
+    for ((i = 1; i <= COMP_CWORD; i++)); do
+        if ((i > 1)) && [[ ${COMP_WORDS[i]} == "=" || ${COMP_WORDS[i-1]} == "=" ]]; then
+            words[${#words[@]}-1]+=${COMP_WORDS[i]}
+        else
+            words+=("${COMP_WORDS[i]}")
+        fi
+    done
+    COMPREPLY=($("%[2]s" ` + completeLiteral + ` "${words[@]}" 2>/dev/null))
+}
+complete -o default -F _%[1]s_complete %[2]s
+`,
+	"zsh": `#compdef %[2]s
+_%[1]s_complete() {
+    local -a candidates
+    candidates=(${(f)"$("%[2]s" ` + completeLiteral + ` "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -Q -- "${candidates[@]}"
+}
+compdef _%[1]s_complete %[2]s
+`,
+	"fish": `# fish completion for %[2]s
+function __%[1]s_complete
+    set -l tokens (commandline -opc)
+    set -l current (commandline -ct)
+    %[2]s ` + completeLiteral + ` $tokens[2..-1] "$current" 2>/dev/null | string unescape
+end
+complete -c %[2]s -f -a '(__%[1]s_complete)'
+`,
+}
+
+// shellNameRegex matches the characters that are not safe to use in shell
+// function names
+var shellNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// SetCompletion sets whether the commander has the built-in completion
+// command, which prints a shell completion script, such as "completion bash".
+// It is listed after the commands of the program. The default is false, so
+// that programs can map a completion command of their own.
+func (c *Commander) SetCompletion(completion bool) {
+
+	if completion == (len(c.completionCommands) > 0) {
+		return
+	}
+	if completion {
+		c.mapCompletion()
+		return
+	}
+
+	commands := c.commands[:0]
+	for _, cmd := range c.commands {
+		if !containsCommand(c.completionCommands, cmd) {
+			commands = append(commands, cmd)
+		}
+	}
+	c.commands = commands
+	c.completionCommands = nil
+
+}
+
+// SetCompletion sets whether the shared Commander used by Go has the built-in
+// completion command.
+//
+// See Commander.SetCompletion.
+func SetCompletion(completion bool) {
+	sharedCommander.SetCompletion(completion)
+}
+
+// containsCommand determines if the commands contain cmd
+func containsCommand(commands []*command, cmd *command) bool {
+
+	for _, command := range commands {
+		if command == cmd {
+			return true
+		}
+	}
+	return false
+
+}
+
+// mapCompletion maps the built-in completion commands, recording the error
+// to be returned from Run if they clash with the commands of the program
+func (c *Commander) mapCompletion() {
+
+	err := c.Map(completionDefinition, "Prints a shell completion script",
+		"Prints a script that enables tab completion for this program in the given shell. For example, add \"source <("+c.appName+" completion bash)\" to your ~/.bashrc.",
+		func(args objx.Map) {
+			fmt.Fprint(c.stdout, c.completionScript(args["shell"].(string)))
+		})
+	if err != nil {
+		return
+	}
+	c.completionCommands = append(c.completionCommands, c.commands[len(c.commands)-1])
+
+	c.MustMap(completeLiteral+" [words=(string)...]", "", "",
+		func(args objx.Map) {
+			// the shells pass the words as they were typed, and insert the
+			// candidates as they are printed
+			words, _ := args["words"].([]string)
+			for i, word := range words {
+				words[i] = unescapeArg(word)
+			}
+			for _, candidate := range c.complete(words) {
+				fmt.Fprintln(c.stdout, escapeArg(candidate))
+			}
+		})
+	c.commands[len(c.commands)-1].hidden = true
+	c.completionCommands = append(c.completionCommands, c.commands[len(c.commands)-1])
+
+}
+
+// completionScript gets the completion script for the shell
+func (c *Commander) completionScript(shell string) string {
+
+	return fmt.Sprintf(completionScripts[shell], shellNameRegex.ReplaceAllString(c.appName, "_"), c.appName)
+
+}
+
+// complete gets the sorted candidates for the last of the words, given the
+// words that precede it. The last word is the (possibly empty) word being
+// completed.
+func (c *Commander) complete(words []string) []string {
+
+	var preceding []string
+	current := ""
+	if len(words) > 0 {
+		preceding, current = words[:len(words)-1], words[len(words)-1]
+	}
+
+	found := make(map[string]bool)
+	var candidates []string
+	for _, cmd := range c.commands {
+		if cmd.hidden || cmd.isDefaultCommand() {
+			continue
+		}
+		for _, candidate := range cmd.candidates(preceding) {
+			// only offer options once the user has started typing one
+			if strings.HasPrefix(candidate, "-") && !strings.HasPrefix(current, "-") {
+				continue
+			}
+			if strings.HasPrefix(candidate, current) && !found[candidate] {
+				found[candidate] = true
+				candidates = append(candidates, candidate)
+			}
+		}
+	}
+
+	sort.Strings(candidates)
+	return candidates
+
+}
+
+// candidates gets the words this command could accept after the preceding
+// words. Literals, list items and option names are offered, but nothing is
+// offered for captures as their values cannot be known.
+func (c *command) candidates(preceding []string) []string {
+
+	// an option waiting for its value cannot be completed
+	if len(preceding) > 0 {
+		for _, o := range c.options {
+			if represents, _, hasValue := o.represents(preceding[len(preceding)-1]); represents && !o.isSwitch() && !hasValue {
+				return nil
+			}
+		}
+	}
+
+	parsed, mismatch := c.parseOptions(preceding)
+	if mismatch != nil {
+		return nil
+	}
+
+	// work out which arguments could come next, following every way the
+	// optional arguments could be matched
+	next := c.skipOptional([]int{0})
+	for _, cmdArg := range parsed.positional {
+		var matched []int
+		for _, argIndex := range next {
+			if argIndex >= len(c.arguments) || !c.arguments[argIndex].represents(cmdArg) {
+				continue
+			}
+			if c.arguments[argIndex].isVariable() {
+				matched = append(matched, argIndex)
+			} else {
+				matched = append(matched, argIndex+1)
+			}
+		}
+		if len(matched) == 0 {
+			return nil
+		}
+		next = c.skipOptional(matched)
+	}
+
+	var candidates []string
+	for _, argIndex := range next {
+		if argIndex >= len(c.arguments) {
+			continue
+		}
+		a := c.arguments[argIndex]
+		switch {
+		case a.isLiteral():
+			candidates = append(candidates, a.names()...)
+		case a.isList():
+			candidates = append(candidates, a.list...)
+		}
+	}
+
+	for _, o := range c.options {
+		if len(parsed.occurrences[o.identifier]) == 0 || o.isRepeatable() {
+			candidates = append(candidates, o.names()...)
+		}
+	}
+
+	return candidates
+
+}
+
+// skipOptional adds the indexes of the arguments that could come next after
+// leaving out any optional groups from each of the argument indexes
+func (c *command) skipOptional(argIndexes []int) []int {
+
+	seen := make(map[int]bool)
+	var next []int
+	var skip func(argIndex int)
+	skip = func(argIndex int) {
+		if seen[argIndex] {
+			return
+		}
+		seen[argIndex] = true
+		next = append(next, argIndex)
+		for _, group := range c.optionals {
+			if group.start == argIndex {
+				skip(group.end)
+			}
+		}
+	}
+	for _, argIndex := range argIndexes {
+		skip(argIndex)
+	}
+	return next
+
+}
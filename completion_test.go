@@ -0,0 +1,122 @@
+package commander
+
+import (
+	"bytes"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func makeCompletionCommander() *Commander {
+
+	c := New()
+	c.SetCompletion(true)
+
+	c.Map(commandString, "", "", func(objx.Map) {
+	})
+	c.Map("delete kind=project|account name=(string) --force", "", "", func(objx.Map) {
+	})
+	c.Map("deploy env=(string) [--timeout=(int)]", "", "", func(objx.Map) {
+	})
+	c.Map("copy [mode=fast|slow] src=(string) [all] dst=(string)", "", "", func(objx.Map) {
+	})
+
+	return c
+
+}
+
+func TestCompletion_complete(t *testing.T) {
+
+	c := makeCompletionCommander()
+
+	assert.Equal(t, c.complete(nil), []string{"completion", "copy", "create", "delete", "deploy", "help"})
+	assert.Equal(t, c.complete([]string{""}), []string{"completion", "copy", "create", "delete", "deploy", "help"})
+	assert.Equal(t, c.complete([]string{"de"}), []string{"delete", "deploy"})
+	assert.Equal(t, c.complete([]string{"create", ""}), []string{"account", "project"})
+	assert.Equal(t, c.complete([]string{"create", "p"}), []string{"project"})
+	assert.Empty(t, c.complete([]string{"create", "project", ""}))
+	assert.Empty(t, c.complete([]string{"create", "unknown", ""}))
+	assert.Equal(t, c.complete([]string{"completion", ""}), []string{"bash", "fish", "zsh"})
+
+	// optional arguments
+	assert.Equal(t, c.complete([]string{"copy", ""}), []string{"fast", "slow"})
+	assert.Equal(t, c.complete([]string{"copy", "fast", ""}), []string{"all"})
+	assert.Equal(t, c.complete([]string{"copy", "a", ""}), []string{"all"})
+	assert.Empty(t, c.complete([]string{"copy", "a", "all", ""}))
+
+	// options
+	assert.Empty(t, c.complete([]string{"delete", "project", "mat", ""}))
+	assert.Equal(t, c.complete([]string{"delete", "project", "mat", "-"}), []string{"--force"})
+	assert.Empty(t, c.complete([]string{"delete", "--force", "project", "mat", "-"}))
+	assert.Equal(t, c.complete([]string{"delete", "--force", ""}), []string{"account", "project"})
+	assert.Empty(t, c.complete([]string{"deploy", "--timeout", ""}))
+	assert.Empty(t, c.complete([]string{"deploy", "--timeout", "30", "--"}))
+	assert.Empty(t, c.complete([]string{"deploy", "--timeout=30", "--"}))
+	assert.Empty(t, c.complete([]string{"deploy", "--timeout="}))
+	assert.Equal(t, c.complete([]string{"deploy", "prod", "--"}), []string{"--timeout"})
+
+}
+
+func TestCompletion_completeCommand(t *testing.T) {
+
+	c := New()
+	c.SetCompletion(true)
+	stdout := new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.Map(`pick item="a b"|"a c"|other`, "", "", func(objx.Map) {
+	})
+
+	// the words are given as they were typed, and the candidates are
+	// printed as they would be typed
+	assert.NoError(t, c.Run([]string{completeLiteral, "pick", `a\ `}))
+	assert.Equal(t, stdout.String(), "a\\ b\na\\ c\n")
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{completeLiteral, "pick", `'o`}))
+	assert.Equal(t, stdout.String(), "other\n")
+
+}
+
+func TestCompletion_SetCompletion(t *testing.T) {
+
+	c := New()
+	stdout := new(bytes.Buffer)
+	c.SetStdout(stdout)
+
+	// the completion command is not mapped unless completion is enabled
+	c.Map("completion", "", "", HandlerFunc)
+	assert.NoError(t, c.Run([]string{"completion"}))
+
+	c = New()
+	c.SetStdout(stdout)
+	c.Map("deploy", "Deploys", "", HandlerFunc)
+	c.SetCompletion(true)
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help"}))
+	assert.Regexp(t, "deploy - Deploys\n\\s+completion shell=bash\\|zsh\\|fish - .*\n\\s+help ", stdout.String())
+
+	c.SetCompletion(false)
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help"}))
+	assert.NotContains(t, stdout.String(), "completion")
+	assert.Error(t, c.Run([]string{completeLiteral, ""}))
+
+}
+
+func TestCompletion_completionScript(t *testing.T) {
+
+	c := New()
+	c.appName = "my-app"
+
+	for shell := range completionScripts {
+		script := c.completionScript(shell)
+		assert.Contains(t, script, "my_app", shell)
+		assert.Contains(t, script, completeLiteral, shell)
+		assert.False(t, strings.Contains(script, "%!"), shell)
+	}
+
+	// bash splits --name=value at the =, so the script joins the words again
+	assert.Contains(t, completionScripts["bash"], `words[${#words[@]}-1]+=${COMP_WORDS[i]}`)
+
+}
@@ -0,0 +1,167 @@
+package commander
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// configExtension is the extension of the config files searched for
+	configExtension string = ".ini"
+
+	// configFileName is the name of the config file searched for in the user
+	// config directory, in a directory named after the application
+	configFileName string = "config" + configExtension
+)
+
+// configValue is a value set in a config file
+type configValue struct {
+	// value is the value as it was written, without quotes
+	value string
+
+	// origin is the path of the file and the line the value was set on, such
+	// as "my-app.ini:3"
+	origin string
+}
+
+// UseConfigFile instructs the commander to take the values of optional
+// captures and options that are not given on the command line from config
+// files, before falling back to their defaults. The files are read the first
+// time the commander is run.
+//
+// With no paths, the files searched for are config.ini in a directory named
+// after the application in the user config directory (such as
+// ~/.config/my-app/config.ini), and a file named after the application in the
+// working directory (such as my-app.ini), which takes precedence. Otherwise
+// the files with the paths are read, with later files taking precedence.
+//
+// Config files are INI files, with a section for each command named after its
+// literals, and a line for each value, keyed by identifier:
+//
+//	; values for all commands
+//	verbose = true
+//
+//	[deploy]
+//	token = "abc123"
+//
+//	[user create]
+//	role = admin
+//
+// Values from the environment (see Env) take precedence over config files.
+func (c *Commander) UseConfigFile(paths ...string) {
+	c.useConfig = true
+	c.configPaths = paths
+	c.config = nil
+}
+
+// loadConfig reads the config files, if the commander uses them and they have
+// not been read already
+func (c *Commander) loadConfig() error {
+
+	if !c.useConfig || c.config != nil {
+		return nil
+	}
+
+	config := make(map[string]map[string]configValue)
+
+	paths := c.configPaths
+	search := len(paths) == 0
+	if search {
+		paths = c.searchConfigPaths()
+	}
+
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if search && os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		err = parseConfig(file, path, config)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.config = config
+	return nil
+
+}
+
+// searchConfigPaths gets the paths of the config files that are searched for,
+// with the path of the file that takes precedence last
+func (c *Commander) searchConfigPaths() []string {
+
+	var paths []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(dir, c.appName, configFileName))
+	}
+	return append(paths, c.appName+configExtension)
+
+}
+
+// parseConfig reads an INI file, adding the values in it to config by section
+// and key. Values outside of any section are added to the section with an
+// empty name.
+func parseConfig(r io.Reader, path string, config map[string]map[string]configValue) error {
+
+	section := ""
+	scanner := bufio.NewScanner(r)
+
+	for number := 1; scanner.Scan(); number++ {
+
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.Join(strings.Fields(line[1:len(line)-1]), delimiterArgumentSeparator)
+			continue
+		}
+
+		parts := strings.SplitN(line, delimiterEquality, 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" {
+			return fmt.Errorf("%s:%d: expected a [section] or a key = value", path, number)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		if config[section] == nil {
+			config[section] = make(map[string]configValue)
+		}
+		config[section][key] = configValue{value: value, origin: fmt.Sprintf("%s:%d", path, number)}
+
+	}
+
+	return scanner.Err()
+
+}
+
+// lookupConfig gets the value set for the identifier in the config files, in
+// the section for this command or else outside of any section, returning
+// where it was set and false if it is not set
+func (c *command) lookupConfig(identifier string) (string, string, bool) {
+
+	if c.commander == nil || c.commander.config == nil {
+		return "", "", false
+	}
+
+	for _, section := range []string{c.name(), ""} {
+		if value, ok := c.commander.config[section][identifier]; ok {
+			return value.value, value.origin, true
+		}
+	}
+	return "", "", false
+
+}
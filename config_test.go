@@ -0,0 +1,186 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const configFile = `; values for all commands
+verbose = true
+
+[fetch]
+retries = 5
+# a comment
+timeout = "10s"
+
+[ user   create ]
+role = 'admin'
+`
+
+func writeConfig(t *testing.T, dir, name, content string) string {
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+
+}
+
+func TestConfig_parseConfig(t *testing.T) {
+
+	config := make(map[string]map[string]configValue)
+
+	if assert.NoError(t, parseConfig(strings.NewReader(configFile), "app.ini", config)) {
+		assert.Equal(t, config[""]["verbose"], configValue{"true", "app.ini:2"})
+		assert.Equal(t, config["fetch"]["retries"], configValue{"5", "app.ini:5"})
+		assert.Equal(t, config["fetch"]["timeout"], configValue{"10s", "app.ini:7"})
+		assert.Equal(t, config["user create"]["role"], configValue{"admin", "app.ini:10"})
+	}
+
+	err := parseConfig(strings.NewReader("[fetch]\nretries\n"), "app.ini", config)
+	if assert.Error(t, err) {
+		assert.Equal(t, err.Error(), "app.ini:2: expected a [section] or a key = value")
+	}
+
+}
+
+func TestConfig_searchConfigPaths(t *testing.T) {
+
+	c := New()
+	c.appName = "my-app"
+
+	paths := c.searchConfigPaths()
+	if dir, err := os.UserConfigDir(); err == nil {
+		assert.Equal(t, paths, []string{filepath.Join(dir, "my-app", "config.ini"), "my-app.ini"})
+	} else {
+		assert.Equal(t, paths, []string{"my-app.ini"})
+	}
+
+}
+
+func TestConfig_Search(t *testing.T) {
+
+	home, work := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("HOME", home)
+	t.Setenv("AppData", home)
+
+	wd, err := os.Getwd()
+	if err != nil || os.Chdir(work) != nil {
+		t.Skip("cannot change the working directory")
+	}
+	defer os.Chdir(wd)
+
+	c := New()
+	c.appName = "my-app"
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		t.Skip("there is no user config directory")
+	}
+	writeConfig(t, dir, filepath.Join("my-app", "config.ini"), "a = user\nb = user\n")
+	writeConfig(t, work, "my-app.ini", "b = project\n")
+
+	var args objx.Map
+	c.Map("show [a=(string)] [b=(string)]", "", "", func(a objx.Map) {
+		args = a
+	})
+	c.UseConfigFile()
+
+	if assert.NoError(t, c.Run([]string{"show"})) {
+		assert.Equal(t, args["a"], "user")
+		assert.Equal(t, args["b"], "project")
+	}
+
+}
+
+func TestConfig_Resolve(t *testing.T) {
+
+	dir := t.TempDir()
+	path := writeConfig(t, dir, "app.ini", configFile)
+
+	c := New()
+	c.SetEnvPrefix("TESTAPP")
+	c.SetStderr(new(bytes.Buffer))
+
+	var args objx.Map
+	c.Map("fetch url=(string) [retries=(int)=3] [--timeout=(duration)=30s] [--verbose] [--user=(string)]", "", "", func(a objx.Map) {
+		args = a
+	}, Env("timeout"))
+	c.Map("user create name=(string) [role=(string)=member]", "", "", HandlerFunc)
+	c.UseConfigFile(path)
+
+	values, err := c.Resolve([]string{"fetch", "example.com", "--user=mat"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, values, []Value{
+			{"url", "example.com", SourceCommandLine, ""},
+			{"retries", int64(5), SourceConfig, path + ":5"},
+			{"timeout", 10 * time.Second, SourceConfig, path + ":7"},
+			{"verbose", true, SourceConfig, path + ":2"},
+			{"user", "mat", SourceCommandLine, ""},
+		})
+	}
+
+	t.Setenv("TESTAPP_TIMEOUT", "1m")
+	values, err = c.Resolve([]string{"fetch", "example.com", "7"})
+	if assert.NoError(t, err) && assert.Equal(t, len(values), 4) {
+		assert.Equal(t, values[1], Value{"retries", int64(7), SourceCommandLine, ""})
+		assert.Equal(t, values[2], Value{"timeout", time.Minute, SourceEnv, "TESTAPP_TIMEOUT"})
+	}
+
+	values, err = c.Resolve([]string{"user", "create", "mat"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, values, []Value{
+			{"name", "mat", SourceCommandLine, ""},
+			{"role", "admin", SourceConfig, path + ":10"},
+		})
+	}
+
+	_, err = c.Resolve([]string{"unknown"})
+	assert.True(t, errors.Is(err, ErrUsage))
+
+	if assert.NoError(t, c.Run([]string{"fetch", "example.com"})) {
+		assert.Equal(t, args["retries"], int64(5))
+		assert.Equal(t, args["verbose"], true)
+	}
+
+	assert.Equal(t, SourceConfig.String(), "config file")
+
+}
+
+func TestConfig_Errors(t *testing.T) {
+
+	dir := t.TempDir()
+
+	c := New()
+	c.SetStderr(new(bytes.Buffer))
+	c.Map("fetch [retries=(int)]", "", "", HandlerFunc)
+
+	c.UseConfigFile(writeConfig(t, dir, "invalid.ini", "[fetch]\nretries = many\n"))
+	err := c.Run([]string{"fetch"})
+	var mismatch *MismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Config, filepath.Join(dir, "invalid.ini")+":2")
+		assert.Equal(t, mismatch.Error(), "setting at "+filepath.Join(dir, "invalid.ini")+":2 'many' is not a valid int for retries: not a whole number")
+	}
+
+	c.UseConfigFile(writeConfig(t, dir, "syntax.ini", "[fetch]\nretries: 3\n"))
+	err = c.Run([]string{"fetch"})
+	if assert.Error(t, err) {
+		assert.Equal(t, err.Error(), filepath.Join(dir, "syntax.ini")+":2: expected a [section] or a key = value")
+	}
+
+	c.UseConfigFile(filepath.Join(dir, "missing.ini"))
+	assert.True(t, os.IsNotExist(c.Run([]string{"fetch"})))
+
+}
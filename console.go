@@ -2,42 +2,335 @@ package commander
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"unicode"
 )
 
-// SetInteractive instructs commander to use the interactive console
+// consolePrompt is the prompt shown for each line in the interactive console
+const consolePrompt string = "> "
+
+// SetInteractive instructs the commander to use the interactive console
+// when it is run with no arguments
+func (c *Commander) SetInteractive(interactive bool) {
+	c.interactive = interactive
+}
+
+// SetInteractive instructs the shared Commander used by Go to use the
+// interactive console
 func SetInteractive(interactive bool) {
-	sharedCommander.interactive = true
+	sharedCommander.SetInteractive(interactive)
+}
+
+// SetHistoryFile sets the path of the file the interactive console keeps its
+// history in between sessions. By default this is a file named after the
+// application in the home directory of the user, such as ~/.myapp_history.
+// An empty path keeps the history for the current session only.
+func (c *Commander) SetHistoryFile(path string) {
+	c.historyFile = path
+}
+
+// SetHistorySize sets the number of lines of history the interactive console
+// keeps. The default is 500.
+func (c *Commander) SetHistorySize(size int) {
+	c.historySize = size
 }
 
 // launchConsole launches the interactive console. The console accepts
 // commands defined by Map(), the same as if you passed them directly
 // on the command line. Each command will run the appropriate handler.
-func launchConsole() {
+//
+// The console returns when the user types quit or exit, or at the end of the
+// input (Ctrl-D in a terminal). Ctrl-C cancels the line being typed, or stops
+// waiting for the handler that is running. Handlers are not cancelled, so an
+// interrupted handler keeps running, and the next command, or the console
+// returning, waits for it to return. Pressing Ctrl-C again stops waiting.
+//
+// When the console is run in a terminal, lines can be edited, previous lines
+// recalled with the up and down arrows, the history searched with Ctrl-R,
+// and commands and their arguments completed with Tab.
+func (c *Commander) launchConsole() {
+
+	reader := bufio.NewReader(c.stdin)
+
+	fd := -1
+	terminal := false
+	if file, ok := c.stdin.(*os.File); ok {
+		fd = int(file.Fd())
+		terminal = isTerminal(fd)
+	}
+
+	lines := &history{size: c.historySize}
+	if terminal && c.historyFile != "" {
+		if err := lines.load(c.historyFile); err != nil {
+			fmt.Fprintln(c.stderr, "An error occured while reading your history:", err)
+		}
+	}
+	editor := newLineEditor(reader, c.stdout, lines)
+	editor.completer = c.completeConsole
+
+	fmt.Fprintf(c.stdout, "\nWelcome to the %s console! Type quit or exit when done.\n\n", c.appName)
 
-	reader := bufio.NewReader(os.Stdin)
+	// interrupts cancel the line or stop waiting for the running handler
+	// rather than killing the process
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	defer signal.Stop(signals)
 
-	fmt.Printf("\nWelcome to the %s console! Type quit or exit when done.\n\n", sharedCommander.appName)
+	// an interrupted handler is waited for before returning, so that it is not
+	// killed when the program exits
+	defer c.waitForInterrupted(signals)
 
 	for {
-		fmt.Printf("\n> ")
-		if line, err := reader.ReadString('\n'); err != nil {
-			fmt.Println("An error occured while reading your input:", err)
-		} else {
+		fmt.Fprintf(c.stdout, "\n")
+
+		line, err := c.readConsoleLine(reader, editor, fd, terminal)
+		if err == errInterrupt {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			fmt.Fprintln(c.stderr, "An error occured while reading your input:", err)
+			return
+		}
+
+		if terminal && c.historyFile != "" {
+			if err := lines.save(c.historyFile); err != nil {
+				fmt.Fprintln(c.stderr, "An error occured while saving your history:", err)
+			}
+		}
+
+		if quit := c.runConsoleLine(line, signals); quit || err == io.EOF {
+			return
+		}
+	}
+
+}
+
+// runConsoleLine runs the command typed into the console, returning true if
+// the user asked to quit. If an interrupt is received on signals while the
+// handler is running, the console stops waiting for it and carries on; the
+// handler is left to finish in the background, and the next command waits for
+// it to return before it runs, so that handlers never run at the same time.
+func (c *Commander) runConsoleLine(line string, signals chan os.Signal) bool {
+
+	args, err := splitLine(strings.TrimRight(line, "\r\n"))
+	if err != nil {
+		fmt.Fprintln(c.stderr, "An error occured while reading your input:", err)
+		return false
+	}
+
+	if len(args) == 0 {
+		return false
+	}
+
+	if len(args) == 1 && (args[0] == "quit" || args[0] == "exit") {
+		return true
+	}
+
+	fmt.Fprintln(c.stdout)
+
+	// forget any interrupt received while the line was being read
+	select {
+	case <-signals:
+	default:
+	}
+
+	if !c.waitForInterrupted(signals) {
+		return false
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.handleInvocation(args)
+	}()
+
+	select {
+	case err := <-done:
+		c.printConsoleError(err)
+	case <-signals:
+		fmt.Fprintln(c.stdout, "\nInterrupted")
+		c.interrupted = done
+	}
+
+	return false
+
+}
+
+// waitForInterrupted waits for the handler the console stopped waiting for
+// when it was interrupted to return, if it is still running, and prints any
+// error it returned. It returns false if another interrupt is received on
+// signals first.
+func (c *Commander) waitForInterrupted(signals chan os.Signal) bool {
+
+	if c.interrupted == nil {
+		return true
+	}
+
+	fmt.Fprintln(c.stdout, "Waiting for the interrupted command to finish...")
+	select {
+	case err := <-c.interrupted:
+		c.interrupted = nil
+		c.printConsoleError(err)
+		return true
+	case <-signals:
+		fmt.Fprintln(c.stdout, "\nInterrupted")
+		return false
+	}
+
+}
+
+// printConsoleError prints the error a handler returned, unless it is a
+// usage error, which has already been printed along with the usage
+func (c *Commander) printConsoleError(err error) {
+
+	if err != nil && !errors.Is(err, ErrUsage) {
+		fmt.Fprintln(c.stderr, "Error:", err)
+	}
 
-			line = strings.Replace(line, "\n", "", -1)
+}
+
+// completeConsole gets the candidates for the last of the words typed into
+// the console, which are the same as for shell completion plus the quit and
+// exit commands
+func (c *Commander) completeConsole(words []string) []string {
+
+	candidates := c.complete(words)
 
-			if line == "quit" || line == "exit" {
-				os.Exit(0)
+	if len(words) == 1 {
+		for _, command := range []string{"exit", "quit"} {
+			if strings.HasPrefix(command, words[0]) {
+				candidates = append(candidates, command)
 			}
+		}
+		sort.Strings(candidates)
+	}
+
+	return candidates
 
-			fmt.Println("")
+}
+
+// readConsoleLine reads a line for the console, using the line editor if the
+// console is running in a terminal
+func (c *Commander) readConsoleLine(reader *bufio.Reader, editor *lineEditor, fd int, terminal bool) (string, error) {
 
-			args := strings.Split(line, " ")
-			handleInvocation(args)
+	if terminal {
+		if restore, err := makeRaw(fd); err == nil {
+			defer restore()
+			return editor.readLine(consolePrompt)
 		}
 	}
 
+	fmt.Fprint(c.stdout, consolePrompt)
+	return reader.ReadString('\n')
+
+}
+
+// splitLine splits a line typed into the console into arguments, the way a
+// shell would. Arguments are separated by runs of whitespace, and may be
+// quoted with single or double quotes to include whitespace. A backslash
+// escapes the next character, except inside single quotes where everything
+// is literal; inside double quotes a backslash only escapes " and \.
+func splitLine(line string) ([]string, error) {
+
+	args, _, _, err := scanLine([]rune(line))
+	if err != nil {
+		return nil, err
+	}
+	return args, nil
+
+}
+
+// scanLine splits a line into arguments the way splitLine does, also getting
+// the index each argument starts at and whether the line ends inside an
+// argument. If the line ends with an unfinished quote or escape, the last
+// argument is returned along with the error.
+func scanLine(line []rune) (args []string, starts []int, inArg bool, err error) {
+
+	var arg []rune
+	var quote rune
+	escaped := false
+
+	for i, r := range line {
+		if !inArg && !unicode.IsSpace(r) {
+			starts = append(starts, i)
+		}
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				arg = append(arg, '\\')
+			}
+			arg = append(arg, r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg = append(arg, r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, string(arg))
+				arg = arg[:0]
+				inArg = false
+			}
+		default:
+			arg = append(arg, r)
+			inArg = true
+		}
+	}
+
+	if inArg {
+		args = append(args, string(arg))
+	}
+
+	switch {
+	case escaped:
+		err = errors.New("the line ends with an unfinished escape")
+	case quote != 0:
+		err = fmt.Errorf("the line has an unterminated %c quote", quote)
+	}
+
+	return args, starts, inArg, err
+
+}
+
+// shellSpecial contains the characters, besides whitespace, that escapeArg
+// escapes because the console or a shell would otherwise give them a meaning
+const shellSpecial string = "\\\"'`$&|;<>()[]{}*?!#~"
+
+// escapeArg gets the argument as it would be typed into the console or a
+// shell, with a backslash before any whitespace or special characters, so
+// that a completed argument is read back as one argument
+func escapeArg(arg string) string {
+
+	var escaped []rune
+	for _, r := range arg {
+		if unicode.IsSpace(r) || strings.ContainsRune(shellSpecial, r) {
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, r)
+	}
+	return string(escaped)
+
+}
+
+// unescapeArg gets a single argument as it was typed into a shell without
+// its quotes and escapes, or unchanged if it is not a single argument
+func unescapeArg(typed string) string {
+
+	args, _, _, _ := scanLine([]rune(typed))
+	if len(args) != 1 {
+		return typed
+	}
+	return args[0]
+
 }
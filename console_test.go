@@ -0,0 +1,168 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsole_splitLine(t *testing.T) {
+
+	for _, test := range []struct {
+		line string
+		args []string
+	}{
+		{``, nil},
+		{"   \t ", nil},
+		{`create project stretchr`, []string{"create", "project", "stretchr"}},
+		{"create  project\tstretchr\r", []string{"create", "project", "stretchr"}},
+		{`create project "My Project"`, []string{"create", "project", "My Project"}},
+		{`create project 'My Project'`, []string{"create", "project", "My Project"}},
+		{`create project My\ Project`, []string{"create", "project", "My Project"}},
+		{`create "" ''`, []string{"create", "", ""}},
+		{`say "He said \"hi\""`, []string{"say", `He said "hi"`}},
+		{`say 'back\slash'`, []string{"say", `back\slash`}},
+		{`say "back\slash"`, []string{"say", `back\slash`}},
+		{`say "back\\slash"`, []string{"say", `back\slash`}},
+		{`say one" two "three`, []string{"say", "one two three"}},
+	} {
+		args, err := splitLine(test.line)
+		if assert.NoError(t, err, test.line) {
+			assert.Equal(t, args, test.args, test.line)
+		}
+	}
+
+	_, err := splitLine(`create "My Project`)
+	assert.Error(t, err)
+
+	_, err = splitLine(`create project\`)
+	assert.Error(t, err)
+
+	_, err = splitLine(`say it's"quoted"`)
+	assert.Error(t, err)
+
+}
+
+func TestConsole_escapeArg(t *testing.T) {
+
+	for _, arg := range []string{"plain", "a b", `it's "quoted"`, `back\slash`, "tab\there", "$HOME|x;y"} {
+		args, err := splitLine(escapeArg(arg))
+		if assert.NoError(t, err, arg) {
+			assert.Equal(t, args, []string{arg}, arg)
+		}
+		assert.Equal(t, unescapeArg(escapeArg(arg)), arg)
+	}
+
+	assert.Equal(t, escapeArg("a b"), `a\ b`)
+	assert.Equal(t, unescapeArg(`"a b`), "a b")
+	assert.Equal(t, unescapeArg("a b"), "a b")
+
+}
+
+func TestConsole_launchConsole(t *testing.T) {
+
+	c := New()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdin(strings.NewReader("create \"My Project\"\nfail\nunknown\nquit\ncreate never\n"))
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+	c.SetInteractive(true)
+
+	var names []string
+	c.Map("create name=(string)", "", "", func(args objx.Map) {
+		names = append(names, args["name"].(string))
+	})
+	c.MapE("fail", "", "", func(objx.Map) error {
+		return errors.New("failed")
+	})
+
+	assert.NoError(t, c.Run(nil))
+	assert.Equal(t, names, []string{"My Project"})
+	assert.Contains(t, stdout.String(), "Welcome to the "+c.appName+" console!")
+	assert.Contains(t, stderr.String(), "Error: failed")
+	assert.Contains(t, stderr.String(), "unknown command 'unknown'")
+
+	// the console ends at the end of the input
+	c.SetStdin(strings.NewReader("create again"))
+	assert.NoError(t, c.Run(nil))
+	assert.Equal(t, names, []string{"My Project", "again"})
+
+}
+
+func TestConsole_runConsoleLine(t *testing.T) {
+
+	c := New()
+	c.SetStdout(new(bytes.Buffer))
+	signals := make(chan os.Signal, 1)
+
+	called := false
+	c.Map("create", "", "", func(objx.Map) {
+		called = true
+	})
+
+	assert.False(t, c.runConsoleLine("create\n", signals))
+	assert.True(t, called)
+
+	assert.False(t, c.runConsoleLine("\n", signals))
+	assert.False(t, c.runConsoleLine("\"unterminated\n", signals))
+	assert.True(t, c.runConsoleLine("quit\n", signals))
+	assert.True(t, c.runConsoleLine(" exit \r\n", signals))
+
+	// an interrupt received while reading the line is forgotten
+	called = false
+	signals <- os.Interrupt
+	assert.False(t, c.runConsoleLine("create", signals))
+	assert.True(t, called)
+
+	// an interrupt stops waiting for a running handler
+	release := make(chan bool)
+	started := make(chan bool)
+	waited := false
+	c.Map("wait", "", "", func(objx.Map) {
+		started <- true
+		<-release
+		waited = true
+	})
+	go func() {
+		<-started
+		signals <- os.Interrupt
+	}()
+	assert.False(t, c.runConsoleLine("wait", signals))
+
+	// the next command waits for the interrupted handler to return
+	c.Map("check", "", "", func(objx.Map) {
+		assert.True(t, waited)
+	})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	assert.False(t, c.runConsoleLine("check", signals))
+	assert.Nil(t, c.interrupted)
+
+	// the error of an interrupted handler is printed once it is waited for,
+	// such as when the console returns
+	stderr := new(bytes.Buffer)
+	c.SetStderr(stderr)
+	release = make(chan bool)
+	c.MapE("slow", "", "", func(objx.Map) error {
+		started <- true
+		<-release
+		return errors.New("too slow")
+	})
+	go func() {
+		<-started
+		signals <- os.Interrupt
+	}()
+	assert.False(t, c.runConsoleLine("slow", signals))
+	close(release)
+	assert.True(t, c.waitForInterrupted(signals))
+	assert.Equal(t, stderr.String(), "Error: too slow\n")
+	assert.Nil(t, c.interrupted)
+
+}
@@ -13,12 +13,28 @@ const (
 	// delimiterListItems is the string that separates a group of literals,
 	// indicating that it is a list
 	delimiterListItems string = "|"
+
+	// delimiterOptionsEnd is the command line argument that ends the options,
+	// so that any following arguments are treated as positional even if they
+	// look like options
+	delimiterOptionsEnd string = "--"
 )
 
 const (
-	submatchKeyKind     string = "kind"
-	submatchKeyType     string = "type"
-	submatchKeyOpen     string = "open"
-	submatchKeyClose    string = "close"
-	submatchKeyVariable string = "variable"
+	// specificityPrefix is the specificity of a literal given as a prefix,
+	// when prefix matching is on
+	specificityPrefix int = iota
+
+	// specificityString is the specificity of a string capture
+	specificityString
+
+	// specificityTyped is the specificity of a capture of any other type, or
+	// with a constraint
+	specificityTyped
+
+	// specificityList is the specificity of a list
+	specificityList
+
+	// specificityLiteral is the specificity of a literal
+	specificityLiteral
 )
@@ -0,0 +1,303 @@
+package commander
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// delimiterConstraint is the string that separates a capture type from its
+	// constraint, as in (int:1..65535)
+	delimiterConstraint string = ":"
+
+	// delimiterRange is the string that separates the bounds of a range
+	delimiterRange string = ".."
+
+	// delimiterPattern is the string that surrounds a pattern, as in
+	// (string:/^[a-z]+$/)
+	delimiterPattern string = "/"
+)
+
+// constraint restricts the values a capture accepts, either to a range, such
+// as 1..65535, or to those matching a pattern, such as /^[a-z]+$/. Ranges of
+// strings restrict their length.
+type constraint struct {
+	// raw is the constraint as it was written in the definition
+	raw string
+
+	// column is the column of the definition the constraint starts at
+	column int
+
+	// min and max are the bounds of a range, converted to the capture type, or
+	// nil if the range is open at that end
+	min, max interface{}
+
+	// length is whether the range restricts the length of a string rather
+	// than its value
+	length bool
+
+	// pattern is the regexp values must match, if the constraint is a pattern
+	pattern *regexp.Regexp
+}
+
+// makeConstraint makes the constraint written as raw at column for the
+// capture type, or returns nil if raw is empty. It panics if the constraint is
+// not valid, which is checked by parseConstraint when the definition is
+// parsed. The constraint of a time is only kept as it was written, as its
+// bounds depend on the time settings of the command; they are converted by
+// command.parseTimeConstraint.
+func makeConstraint(captureType, raw string, column int) *constraint {
+
+	if captureType == timeType && raw != "" {
+		return &constraint{raw: raw, column: column}
+	}
+
+	c, err := parseConstraint(captureType, raw, nil)
+	if err != nil {
+		panic(err.Error())
+	}
+	if c != nil {
+		c.column = column
+	}
+	return c
+
+}
+
+// parseConstraint makes the constraint written as raw for the capture type, or
+// returns nil if raw is empty, returning an error if the constraint cannot be
+// applied to the type. The bounds of a range are converted with the settings
+// of the command, or the defaults if it is nil.
+func parseConstraint(captureType, raw string, cmd *command) (*constraint, error) {
+
+	if raw == "" {
+		return nil, nil
+	}
+	if !isRegisteredType(captureType) {
+		return nil, fmt.Errorf("the capture type (%s) is not registered", captureType)
+	}
+
+	c := &constraint{raw: raw}
+
+	if len(raw) > 1 && strings.HasPrefix(raw, delimiterPattern) && strings.HasSuffix(raw, delimiterPattern) {
+		pattern, err := regexp.Compile(raw[1 : len(raw)-1])
+		if err != nil {
+			return nil, fmt.Errorf("the pattern %s is not valid: %s", raw, err)
+		}
+		c.pattern = pattern
+		return c, nil
+	}
+
+	bounds := strings.SplitN(raw, delimiterRange, 2)
+	if len(bounds) != 2 || bounds[0] == "" && bounds[1] == "" {
+		return nil, fmt.Errorf("the constraint %s must be a range such as 1..10 or a pattern such as /^[a-z]+$/", raw)
+	}
+
+	c.length = captureType == "string"
+	for i, bound := range bounds {
+		if bound == "" {
+			continue
+		}
+		value, err := c.convertBound(bound, captureType, cmd)
+		if err != nil {
+			return nil, fmt.Errorf("the bound %s is not valid: %s", bound, err)
+		}
+		if i == 0 {
+			c.min = value
+		} else {
+			c.max = value
+		}
+	}
+	if c.min != nil && c.max != nil {
+		if order, _ := compareValues(c.min, c.max); order > 0 {
+			return nil, fmt.Errorf("the range %s is empty", raw)
+		}
+	}
+
+	return c, nil
+
+}
+
+// convertBound converts a bound of a range to a value that can be compared
+// with the values of the capture type, using the settings of the command
+func (c *constraint) convertBound(bound, captureType string, cmd *command) (interface{}, error) {
+
+	if c.length {
+		length, err := strconv.ParseInt(bound, 10, 0)
+		if err != nil || length < 0 {
+			return nil, errors.New("lengths must be whole numbers of zero or more")
+		}
+		return length, nil
+	}
+
+	value, err := cmd.convert(bound, captureType, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := compareValues(value, value); !ok {
+		return nil, errors.New("values of the type cannot be compared")
+	}
+	return value, nil
+
+}
+
+// bounds gets values at the bounds of the range, which any overlapping range
+// also accepts, or nil if c is nil or is not a range. The bounds of a range of
+// lengths are given as strings of those lengths.
+func (c *constraint) bounds() []string {
+
+	if c == nil || c.pattern != nil {
+		return nil
+	}
+
+	var bounds []string
+	for _, bound := range strings.SplitN(c.raw, delimiterRange, 2) {
+		switch {
+		case bound == "":
+		case c.length:
+			length, _ := strconv.Atoi(bound)
+			bounds = append(bounds, strings.Repeat("a", length))
+		default:
+			bounds = append(bounds, bound)
+		}
+	}
+	return bounds
+
+}
+
+// isPattern determines if the constraint is a pattern
+func (c *constraint) isPattern() bool {
+	return c != nil && c.pattern != nil
+}
+
+// String gets the constraint as it was written in the definition, or an empty
+// string if c is nil
+func (c *constraint) String() string {
+
+	if c == nil {
+		return ""
+	}
+	return c.raw
+
+}
+
+// check determines if the value (converted from the cmdArg) satisfies the
+// constraint, returning an error describing the violation if not
+func (c *constraint) check(cmdArg string, value interface{}) error {
+
+	if c == nil {
+		return nil
+	}
+
+	if c.pattern != nil {
+		if !c.pattern.MatchString(cmdArg) {
+			return fmt.Errorf("does not match %s", c.raw)
+		}
+		return nil
+	}
+
+	if c.length {
+		value = int64(utf8.RuneCountInString(cmdArg))
+	}
+
+	if order, ok := compareValues(value, c.min); c.min != nil && ok && order < 0 {
+		if c.length {
+			return fmt.Errorf("shorter than %v characters", c.min)
+		}
+		return fmt.Errorf("less than %v", c.min)
+	}
+	if order, ok := compareValues(value, c.max); c.max != nil && ok && order > 0 {
+		if c.length {
+			return fmt.Errorf("longer than %v characters", c.max)
+		}
+		return fmt.Errorf("more than %v", c.max)
+	}
+	return nil
+
+}
+
+// describe describes the values the constraint accepts, to follow the
+// description of the capture type, such as "between 1 and 65535"
+func (c *constraint) describe() string {
+
+	if c.pattern != nil {
+		return "matching " + c.raw
+	}
+
+	if c.length {
+		switch {
+		case c.min == nil:
+			return fmt.Sprintf("of at most %v characters", c.max)
+		case c.max == nil:
+			return fmt.Sprintf("of at least %v characters", c.min)
+		case c.min == c.max:
+			return fmt.Sprintf("of exactly %v characters", c.min)
+		}
+		return fmt.Sprintf("of %v to %v characters", c.min, c.max)
+	}
+
+	switch {
+	case c.min == nil:
+		return fmt.Sprintf("of at most %v", c.max)
+	case c.max == nil:
+		return fmt.Sprintf("of at least %v", c.min)
+	}
+	return fmt.Sprintf("between %v and %v", c.min, c.max)
+
+}
+
+// compareValues compares two values of the same type, returning -1, 0 or 1 if
+// a is less than, equal to or more than b, and false if the values cannot be
+// compared
+func compareValues(a, b interface{}) (int, bool) {
+
+	var less, more bool
+
+	switch a := a.(type) {
+	case int64:
+		b, ok := b.(int64)
+		if !ok {
+			return 0, false
+		}
+		less, more = a < b, a > b
+	case uint64:
+		b, ok := b.(uint64)
+		if !ok {
+			return 0, false
+		}
+		less, more = a < b, a > b
+	case float64:
+		b, ok := b.(float64)
+		if !ok {
+			return 0, false
+		}
+		less, more = a < b, a > b
+	case time.Duration:
+		b, ok := b.(time.Duration)
+		if !ok {
+			return 0, false
+		}
+		less, more = a < b, a > b
+	case time.Time:
+		b, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		less, more = a.Before(b), a.After(b)
+	default:
+		return 0, false
+	}
+
+	switch {
+	case less:
+		return -1, true
+	case more:
+		return 1, true
+	}
+	return 0, true
+
+}
@@ -0,0 +1,166 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestConstraint_CaptureType(t *testing.T) {
+
+	a := makeArgument("port=(int:1..65535)")
+	assert.Equal(t, a.captureType, "int")
+	assert.Equal(t, a.constraint.String(), "1..65535")
+
+	a = makeArgument("name=(string:/^a:b$/)")
+	assert.Equal(t, a.captureType, "string")
+	assert.Equal(t, a.constraint.String(), "/^a:b$/")
+
+	a = makeArgument("name=(string)")
+	assert.Equal(t, a.captureType, "string")
+	assert.Nil(t, a.constraint)
+
+}
+
+func TestConstraint_makeConstraint(t *testing.T) {
+
+	assert.Nil(t, makeConstraint("int", "", 0))
+
+	c := makeConstraint("int", "1..65535", 0)
+	assert.Equal(t, c.min, int64(1))
+	assert.Equal(t, c.max, int64(65535))
+	assert.Equal(t, c.describe(), "between 1 and 65535")
+
+	c = makeConstraint("duration", "1s..", 0)
+	assert.Equal(t, c.min, time.Second)
+	assert.Nil(t, c.max)
+	assert.Equal(t, c.describe(), "of at least 1s")
+
+	c = makeConstraint("string", "..20", 0)
+	assert.True(t, c.length)
+	assert.Equal(t, c.describe(), "of at most 20 characters")
+	assert.Equal(t, makeConstraint("string", "3..20", 0).describe(), "of 3 to 20 characters")
+	assert.Equal(t, makeConstraint("string", "2..2", 0).describe(), "of exactly 2 characters")
+
+	c = makeConstraint("string", "/^[a-z][a-z0-9-]*$/", 0)
+	assert.NotNil(t, c.pattern)
+	assert.Equal(t, c.describe(), "matching /^[a-z][a-z0-9-]*$/")
+
+	assert.Panics(t, func() {
+		makeConstraint("int", "1-10", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("int", "..", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("int", "a..b", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("int", "10..1", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("string", "a..", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("bool", "false..true", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("string", "/[/", 0)
+	})
+	assert.Panics(t, func() {
+		makeConstraint("email", "/@/", 0)
+	})
+
+}
+
+func TestConstraint_check(t *testing.T) {
+
+	tests := []struct {
+		captureType, constraint, arg, err string
+	}{
+		{"int", "1..65535", "1", ""},
+		{"int", "1..65535", "65535", ""},
+		{"int", "1..65535", "0", "less than 1"},
+		{"int", "1..65535", "65536", "more than 65535"},
+		{"uint", "..10", "11", "more than 10"},
+		{"float", "0..1", "0.5", ""},
+		{"float", "0..1", "1.5", "more than 1"},
+		{"duration", "1s..1m", "2m", "more than 1m0s"},
+		{"string", "3..5", "abc", ""},
+		{"string", "3..5", "ab", "shorter than 3 characters"},
+		{"string", "3..5", "abcdef", "longer than 5 characters"},
+		{"string", "3..5", "äöü", ""},
+		{"string", "/^[a-z][a-z0-9-]*$/", "my-app", ""},
+		{"string", "/^[a-z][a-z0-9-]*$/", "My-app", "does not match /^[a-z][a-z0-9-]*$/"},
+	}
+
+	for _, test := range tests {
+		a := makeArgument("value=(" + test.captureType + ":" + test.constraint + ")")
+		value, err := a.convert(test.arg)
+		if test.err == "" {
+			assert.NoError(t, err, test.arg)
+			assert.NotNil(t, value, test.arg)
+			assert.True(t, a.represents(test.arg), test.arg)
+		} else if assert.Error(t, err, test.arg) {
+			assert.Equal(t, err.Error(), test.err)
+			assert.Nil(t, value, test.arg)
+			assert.False(t, a.represents(test.arg), test.arg)
+		}
+	}
+
+}
+
+func TestConstraint_Commander(t *testing.T) {
+
+	c := New()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+
+	var args objx.Map
+	c.Map("serve kind=http|https port=(int:1..65535) name=(string:/^[a-z][a-z0-9-]*$/) [--workers=(int:1..)]", "Serves", "Serves an app.", func(a objx.Map) {
+		args = a
+	}, ListHelp("kind", map[string]string{"https": "Serves with TLS"}))
+
+	if assert.NoError(t, c.Run([]string{"serve", "https", "8443", "my-app", "--workers", "4"})) {
+		assert.Equal(t, args["port"], int64(8443))
+		assert.Equal(t, args["name"], "my-app")
+		assert.Equal(t, args["workers"], int64(4))
+	}
+
+	tests := []struct {
+		args    []string
+		message string
+	}{
+		{[]string{"serve", "http", "70000", "my-app"}, "argument 3 '70000' is not a valid int between 1 and 65535 for port: more than 65535"},
+		{[]string{"serve", "http", "80", "My-App"}, "argument 4 'My-App' is not a string matching /^[a-z][a-z0-9-]*$/ for name: does not match /^[a-z][a-z0-9-]*$/"},
+		{[]string{"serve", "http", "80", "app", "--workers=0"}, "option --workers '0' is not a valid int of at least 1 for workers: less than 1"},
+	}
+
+	for _, test := range tests {
+		err := c.Run(test.args)
+		var mismatch *MismatchError
+		if assert.True(t, errors.As(err, &mismatch), "%v", test.args) {
+			assert.Equal(t, mismatch.Error(), test.message)
+		}
+	}
+
+	assert.NoError(t, c.Run([]string{"help", "serve"}))
+	assert.Contains(t, stdout.String(), "    kind - one of http|https\n        https - Serves with TLS\n")
+	assert.Contains(t, stdout.String(), "    port - a valid int between 1 and 65535\n")
+	assert.Contains(t, stdout.String(), "    name - a string matching /^[a-z][a-z0-9-]*$/\n")
+	assert.Contains(t, stdout.String(), "    --workers - a valid int of at least 1\n")
+
+	assert.Panics(t, func() {
+		c.MustMap("deploy kind=http|https", "", "", func(objx.Map) {
+		}, ListHelp("kind", map[string]string{"ftp": "Not in the list"}))
+	})
+	assert.Panics(t, func() {
+		c.MustMap("deploy kind=http|https", "", "", func(objx.Map) {
+		}, ListHelp("type", nil))
+	})
+
+}
@@ -0,0 +1,44 @@
+package commander
+
+import (
+	"fmt"
+	"github.com/stretchr/commander/syntax"
+)
+
+// parseDefinition parses the definition into its nodes, returning a
+// *DefinitionError if it does not follow the grammar (see the syntax package)
+func parseDefinition(definition string) (*syntax.Definition, error) {
+
+	parsed, err := syntax.Parse(definition)
+	if syntaxErr, ok := err.(*syntax.Error); ok {
+		return nil, &DefinitionError{Definition: definition, Column: syntaxErr.Column, Message: syntaxErr.Message}
+	}
+	return parsed, err
+
+}
+
+// checkCaptureType ensures the capture type starting at column is registered
+// and its constraint is valid, returning the column of the problem and an
+// error describing it if not
+func checkCaptureType(captureType string, column int, constraint string) (int, error) {
+
+	if !isRegisteredType(captureType) {
+		return column, fmt.Errorf("the capture type (%s) is not registered", captureType)
+	}
+	if captureType == timeType {
+		// the constraint of a time is checked once the time settings of the
+		// command are known, by command.parseTimeConstraint
+		return 0, nil
+	}
+	if _, err := parseConstraint(captureType, constraint, nil); err != nil {
+		return constraintColumn(column, captureType), err
+	}
+	return 0, nil
+
+}
+
+// constraintColumn gets the column the constraint of the capture type starting
+// at column starts at
+func constraintColumn(column int, captureType string) int {
+	return column + len(captureType) + len(delimiterConstraint)
+}
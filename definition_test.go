@@ -0,0 +1,105 @@
+package commander
+
+import (
+	"errors"
+	"github.com/stretchr/commander/syntax"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// singleNode parses rawArg, which defines a single argument or option, returning
+// its node and whether it is in [ ] square brackets, or nil if it does not
+// define exactly one
+func singleNode(rawArg string) (syntax.Node, bool) {
+
+	parsed, err := syntax.Parse(rawArg)
+	if err != nil || len(parsed.Nodes) != 1 {
+		return nil, false
+	}
+	if optional, ok := parsed.Nodes[0].(*syntax.Optional); ok && len(optional.Nodes) == 1 {
+		return optional.Nodes[0], true
+	}
+	return parsed.Nodes[0], false
+
+}
+
+func TestDefinition_Errors(t *testing.T) {
+
+	tests := []struct {
+		definition string
+		column     int
+		message    string
+	}{
+		{"add  num=(int)", 5, "expected an argument, but found a space"},
+		{"add num=(int", 9, "the capture type is not closed with )"},
+		{"add num=()", 10, "expected a capture type"},
+		{"add num=(int)x", 14, "unexpected x after the capture type"},
+		{"add num=(in|t)", 12, "unexpected | in the capture type"},
+		{"add num=(int:)", 14, "expected a constraint after :"},
+		{"add num=(nope)", 10, "the capture type (nope) is not registered"},
+		{"add num=(int:9..1)", 14, "the range 9..1 is empty"},
+		{"add [num=(int)", 5, "the optional argument is not closed with ]"},
+		{"add num=(int)]", 14, "unexpected ]"},
+		{"add []", 5, "expected an argument between [ and ]"},
+		{"add =(int)", 5, "expected an identifier before ="},
+		{"add a|b", 6, "unexpected |, a list needs an identifier, as in kind=a|b"},
+		{"add a(b)", 6, "unexpected ("},
+		{`say ""`, 5, "a literal may not be empty"},
+		{"add n(um=(int)", 6, "unexpected ("},
+		{"add kind=a||b", 12, "expected a list item"},
+		{"add kind=a|(b)", 12, "unexpected ( in the list"},
+		{"add -=x", 5, "expected an option such as --name, -n or --name=(type)"},
+		{"add --timeout=(nope)", 16, "the capture type (nope) is not registered"},
+		{"add --timeout=(int:a..)", 20, "the bound a is not valid: not a whole number"},
+		{"add [--force", 5, "the optional argument is not closed with ]"},
+		{"add --force]", 12, "unexpected ]"},
+		{"add --force --force", 13, "the option force is already in the definition"},
+		{"add [--tag=(string)=a...]", 6, "a default may not be given for tag, as it can be repeated"},
+		{"add nums=(int)... num=(int)", 19, "a variable argument may only appear at the end, but nums is followed by num=(int)"},
+		{"add num=(int)=1", 5, "a default may only be given for an optional argument that is not variable, not num"},
+		{"add [num=(int)=one]", 6, "the default one of num is not a valid int: not a whole number"},
+	}
+
+	c := New()
+	for _, test := range tests {
+		err := c.Map(test.definition, "", "", HandlerFunc)
+		var definitionErr *DefinitionError
+		if assert.True(t, errors.As(err, &definitionErr), test.definition) {
+			assert.Equal(t, definitionErr, &DefinitionError{test.definition, test.column, test.message})
+		}
+	}
+
+	err := c.Map("add num=(int", "", "", HandlerFunc)
+	assert.Equal(t, err.Error(), `definition "add num=(int" column 9: the capture type is not closed with )`)
+
+	err = c.Map("add kind=a|b", "", "", HandlerFunc, ListHelp("type", nil))
+	assert.Equal(t, err.Error(), `definition "add kind=a|b": help was given for the list type, which is not in the definition`)
+
+	err = c.Map("add", "", "", nil)
+	assert.Equal(t, err.Error(), `definition "add": a handler must be given`)
+
+}
+
+func TestDefinition_Map(t *testing.T) {
+
+	c := New()
+
+	assert.NoError(t, c.Map("add num=(int)", "", "", HandlerFunc))
+	assert.NoError(t, c.Run([]string{"add", "1"}))
+
+	err := c.Map("add n=(int)", "", "", HandlerFunc)
+	assert.Equal(t, err.Error(), `definition "add n=(int)": it is ambiguous with (add num=(int)), as they can represent the same arguments just as specifically`)
+	assert.Panics(t, func() {
+		c.MustMap("add n=(int)", "", "", HandlerFunc)
+	})
+
+	// the first error is returned from Run
+	assert.Equal(t, c.Run([]string{"add", "1"}), err)
+
+	g := New().Group("user", "")
+	assert.Error(t, g.Map("create name=(string", "", "", HandlerFunc))
+	assert.NotPanics(t, func() {
+		g.MustMap("create name=(string)", "", "", HandlerFunc)
+	})
+
+}
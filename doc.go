@@ -24,9 +24,11 @@ Features
 Commander provides the following features:
 
   * Automatic usage help generation
-  * Typed arguments
+  * Typed arguments, with custom capture types
   * Optional arguments
   * Literal (and list literal) arguments
+  * Named options and switches
+  * Shell completion for bash, zsh and fish
 
 Usage
 
@@ -53,6 +55,27 @@ In the `main` func (in the `main` package) you call the `commander.Go` func like
 
     }
 
+If your program needs more than one independent set of commands (or you want to test your commands
+without sharing any global state) use `commander.New` to make a Commander, map the commands on it, and
+call its Run method with the arguments:
+
+    c := commander.New()
+    c.Map({definition}, {summary}, {description}, {handler})
+    c.Run(os.Args[1:])
+
+By default a Commander reads the console from os.Stdin, writes usage, help and the console to
+os.Stdout, and writes errors (and the usage shown when the arguments do not match a command) to
+os.Stderr.  Use SetStdin, SetStdout and SetStderr to embed it somewhere else, or to capture its
+output in tests.
+
+Map returns a *DefinitionError if the definition is not valid, giving the column of the problem:
+
+    definition "add num=(int" column 9: the capture type is not closed with )
+
+The command is not mapped, and Run returns the error instead of running anything, so that it is
+not lost if it is not checked.  MustMap panics with the error instead, which suits definitions that
+are fixed in the program.
+
 {definition} - The definition is a string that describes the mapping of the command.
 
 {summary} - The summary is a tiny overview of what the command does.
@@ -66,13 +89,32 @@ Handler Func
 The Handler func is a normal func that takes a `map[string]interface{}` as its only argument, and
 returns nothing.
 
-The argument will contain a map of the arguments described in the definition.
+The argument will contain a map of the arguments described in the definition.  Captured
+values are converted to their capture type before the handler is called, so an (int) capture
+will be an int64, a (uint) capture a uint64, a (bool) capture a bool and a (time) capture a
+time.Time.  Variable arguments are collected into a slice of the capture type, for example
+[]string for (string)... and []int64 for (int)...  If a registered type converts values to
+different types, they are collected into an []interface{}.
+
+If a command can fail, map it with `commander.MapE` instead, passing a func that takes the same
+argument and returns an error.  If the handler returns an error, `commander.Go` prints it to stderr
+and exits with a non-zero status.  If the arguments given do not match any command, the usage is
+printed and the program exits with status 2, after a message explaining what did not match, such
+as "argument 3 'abc' is not a valid int for num: not a whole number".  Programs that call Run
+themselves get this as a *MismatchError, so they can present it their own way.
 
 Definitions
 
 A definition is a string that describes the command, including arguments, so that Commander knows when to
 called the associated handler func.
 
+The arguments and options of a definition are separated by single spaces.  Words containing spaces or
+the characters [ ] ( ) = | " can be put in " " double quotes, or those characters escaped with \, as
+in `say "hello world"` or `pick kind=a\|b|c`.  A literal starting with - must be quoted so it is not
+taken for an option, and a literal may not be empty.  The full grammar is given in the syntax package,
+which parses definitions into a tree that tools such as documentation generators can use, and prints
+them back out.
+
 Literal
 
 A literal is any string that is not contained inside ( ) and is not followed by =
@@ -95,15 +137,134 @@ A capture type is a string contained in ( ) parenthesis.
 
 The string inside the ( ) defines what type is required. If the argument cannot be represented by this type, an error will occur.
 
+The built-in types are:
+
+    string               the argument as it is
+    int, int64           an int64
+    uint, uint64         a uint64
+    float, float64       a float64
+    bool                 a bool
+    time                 a time.Time
+    duration             a time.Duration, such as 30s or 1h30m
+    url                  a *url.URL, which must have a scheme
+    ip                   a net.IP
+    cidr                 a *net.IPNet, such as 10.0.0.0/8
+    file                 the path of an existing file, as a string
+    dir                  the path of an existing directory, as a string
+    json                 a JSON object, as a map[string]interface{}
+
+Other types can be added with RegisterType before the definitions that use them are mapped,
+giving a func that converts the argument to a value (or returns an error if it cannot) and a
+description used in help:
+
+    commander.RegisterType("semver", parseSemver, "a semantic version such as 1.2.3")
+    commander.Map("deploy env=(string) version=(semver)", ...)
+
+Mapping a definition with a type that has not been registered returns an error.
+
+Constraints
+
+A capture type may be followed by a : colon and a constraint on the values it accepts, which is
+checked when the arguments are matched and shown in the help for the command:
+
+    port=(int:1..65535)               a range, which may be open at either end, such as (int:1..)
+    name=(string:3..20)               for strings, a range of lengths
+    name=(string:/^[a-z][a-z0-9-]*$/) a pattern, which may not contain spaces (use \s instead)
+
+A value that breaks the constraint is reported with the reason, such as "argument 2 '70000' is not
+a valid int between 1 and 65535 for port: more than 65535".  The items of a list can be given help
+text with the ListHelp option when the command is mapped.
+
+Times
+
+A (time) capture accepts the layouts in the time package, such as RFC 3339, as well as dates
+such as 2006-01-02 and 2006-01-02 15:04, and clock times such as 3:04PM (which are on the current
+day).  Times given without a time zone are in UTC.  Use SetTimeLayouts and SetTimeLocation to
+change these, and SetRelativeTimes to also accept times relative to now, such as now, today,
+yesterday, tomorrow, -2h, +30m or -1w3d.  The same settings can be given for a single command
+when it is mapped:
+
+    commander.Map("report from=(time) [to=(time)]", "Reports", "", report,
+      commander.RelativeTimes(true), commander.TimeLocation(time.Local))
+
+The bounds of a range of times, such as (time:2024-01-01..), are read with the same settings as
+the values of the capture.  Bounds such as now or -1w are relative to when the command is run.
+
 Optional Argument
 
-An optional argument is surrounded by [ ] square brackets.
+An optional argument is surrounded by [ ] square brackets. Captures, lists and literals can all be
+optional, and optional arguments may appear anywhere in the definition:
+
+    copy src=(string) [mode=fast|slow] dst=(string)
+    remove [all] kind=user|group
+
+Several arguments in the same [ ] are left out together, and groups may be nested, so in the
+following dst may be given without mode, but mode only along with dst:
+
+    copy src=(string) [dst=(string) [mode=fast|slow]]
+
+When the arguments could match more than one way, optional arguments are given values from left to
+right: an earlier optional argument takes a value in preference to a later one, as long as the rest
+of the arguments still match.  So `copy a fast b` sets mode to fast, while `copy a fast` leaves mode
+out and sets dst to fast.
+
+An optional capture may be given a default, which is passed to your handler func (as a value of
+the capture type) when the argument is not given, and shown in the help for the command:
+
+    [retries=(int)=3]
+
+Options that take a value may be given a default the same way, such as --timeout=(duration)=30s.
+
+Environment Variables
+
+Optional captures and options can be bound to environment variables with the Env option when the
+command is mapped, so that they are taken from the environment when they are not given on the
+command line (and before falling back to their defaults):
+
+    commander.Map("deploy env=(string) [token=(string)]", "Deploys", "", deploy,
+      commander.Env("token"))
+
+The variable is named after the identifier with a prefix derived from the name of the program, so
+the token of a program called my-app is taken from MY_APP_TOKEN.  Use SetEnvPrefix to change the
+prefix, or EnvVar to bind an identifier to a variable with any name.  The variables are shown in
+the help for the command.
+
+Config Files
+
+Call UseConfigFile to also take the values of optional captures and options from INI files, with a
+section for each command named after its literals (values outside any section apply to every
+command).  By default the files searched for are config.ini in a directory named after the program
+in the user config directory, and a file named after the program (such as please.ini) in the working
+directory:
+
+    verbose = true
+
+    [create]
+    description = "Made by please"
+
+Values on the command line come first, then environment variables, then config files, then
+defaults.  Resolve gets the values a command would be given and where each came from, which helps
+when debugging.
 
 Variable Arguments
 
 A variable argument is defined by placing "..." (three period characters) after a capture type
 It is only valid as the last argument in the command string.
 
+Options
+
+An option is a named flag, starting with - or -- dashes.  Options are always optional, and may
+be given anywhere on the command line.  Everything after a -- argument is treated as positional.
+
+    --force            a switch, which will be true if given and false otherwise
+    --verbose|-v       a switch with a short alias
+    -v...              a repeatable switch, which will be the number of times it was given
+    --timeout=(int)    an option taking a value of the capture type (--timeout=30 or --timeout 30)
+    --tag=(string)...  a repeatable option, which will be a slice of the values given
+
+The value of an option is added to the map passed to your handler func, using the long name
+(or the short name if there is no long name) as the key.
+
 Examples
 
 If we wanted to provide a command-line tool that allowed you to create two types of objects, we
@@ -143,11 +304,88 @@ calls would NOT hit the same handler func:
 
 In order to provide that functionality, another Map call would have to be made.
 
+Choosing a Command
+
+Only one handler func is run for the arguments.  When they match more than one command, the most
+specific command is chosen, comparing the arguments each command matched from left to right:
+literals beat lists, lists beat typed captures (or captures with a constraint), and typed captures
+beat (string) captures.  So with these mappings:
+
+    show id=(int)
+    show name=(string)
+
+`please show 42` runs the first and `please show mat` runs the second.  Map returns an error if a
+command could match the same arguments just as specifically as one already mapped, such as
+`show other=(string)` here, or `show n=(uint)` or `show n=(float)`, which also accept 42.  Captures
+whose ranges do not overlap, such as `(int:1..4)` and `(int:5..20)`, can be mapped in the same place
+to tell commands apart.  Whether two patterns can match the same argument cannot always be known,
+so captures with patterns are only warned about.
+
+Definitions are analysed when they are mapped.  Besides returning errors such as these, Map records
+warnings, such as a (string) capture that shadows an (int) capture, or a command that more
+specific commands leave no arguments for.  Use Diagnostics to get them, or Analyze to check a
+definition without mapping it:
+
+    for _, d := range c.Analyze("show name=(string)") {
+      fmt.Println(d)
+    }
+
+Aliases and Prefixes
+
+Give a command other names with the Alias option, which replace the literals its definition starts
+with.  The aliases are shown in the help for the command:
+
+    commander.Map("remove id=(int)", "Removes", "", remove, commander.Alias("rm"))
+
+Call SetPrefixMatching(true) to let the literals of commands be given as any prefix of them, so
+`please proj cre mat` runs `project create name=(string)`.  A literal given in full, or a capture
+that represents the argument, is chosen over a literal it is a prefix of, and a prefix that could
+stand for more than one command is reported along with the commands it could be, such as "argument
+2 'c' is ambiguous, as it could be copy or create".
+
+Groups
+
+Related commands can be mapped on a group of a Commander, which has its own summary and help.
+Groups may be nested to any depth, and a DefaultCommand mapped on a group is run when the group
+is invoked with no further arguments:
+
+    user := c.Group("user", "Manages users")
+    user.Map("create name=(string)", "Creates a user", "", createUser)
+    user.Map(commander.DefaultCommand, "Prints the current user", "", currentUser)
+
+Here `please user create mat` runs createUser, and `please help user` lists only the user commands.
+A group name must be a single literal; mapping on a group with any other name returns an error.
+
+Shell Completion
+
+Call SetCompletion(true) to add a built-in completion command, listed after your own commands, that
+prints a tab completion script for bash, zsh or fish.  The script calls back into your program to
+get the candidates for the word being completed, so literals, list items and options complete
+automatically:
+
+    source <(please completion bash)
+
 Interactive Mode
 
 If you would like to enable an interactive console for your application to run your mapped commands,
 call SetInteractive(true) inside your Go() call. This will enable the interactive console when no arguments
 are provided to the program.
 
+The console runs until the user types quit or exit, or the input ends (Ctrl-D in a terminal), and
+then returns from Go (or Run) so that deferred cleanup in your main func still happens.  Ctrl-C
+cancels the line being typed, or stops waiting for a handler that is taking too long, rather than
+killing the program.  The handler is not cancelled: it keeps running in the background, and the
+next command waits for it to return before it runs, as does the console before it returns, so that
+the handler is not killed when the program exits.  Pressing Ctrl-C again stops waiting.
+
+When the console runs in a terminal, lines can be edited with the usual keys (arrows, Home, End,
+Ctrl-A, Ctrl-E, Ctrl-K, Ctrl-U, Ctrl-W), previous lines recalled with the up and down arrows, and
+the history searched with Ctrl-R.  Pressing Tab completes command literals, list items and options
+the same way shell completion does, listing the candidates when there is more than one.
+
+The history is kept between sessions in a file named after the application in the home directory
+of the user; use SetHistoryFile and SetHistorySize to change where it is kept and how many lines
+are kept.
+
 */
 package commander
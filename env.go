@@ -0,0 +1,121 @@
+package commander
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// SetEnvPrefix sets the prefix of the environment variables that arguments
+// bound with Env fall back to, such as MYAPP for MYAPP_TOKEN. The default is
+// derived from the name of the application, so my-app has the prefix MY_APP.
+// An empty prefix leaves the variables unprefixed.
+func (c *Commander) SetEnvPrefix(prefix string) {
+	c.envPrefix = prefix
+}
+
+// Env binds the optional arguments and options with the identifiers to the
+// environment variables named after them, so that an argument that is not
+// given on the command line is taken from the environment before falling
+// back to its default. For example, with the prefix MYAPP, the identifier
+// token is bound to MYAPP_TOKEN.
+//
+// Map returns an error if an identifier is not that of an optional capture or
+// an option that is not repeatable.
+//
+// See Commander.SetEnvPrefix.
+func Env(identifiers ...string) MapOption {
+	return func(cmd *command) error {
+		for _, identifier := range identifiers {
+			if err := cmd.bindEnv(identifier, ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// EnvVar binds the optional argument or option with the identifier to the
+// environment variable with the name, which is used exactly as it is given.
+//
+// See Env.
+func EnvVar(identifier, name string) MapOption {
+	return func(cmd *command) error {
+		return cmd.bindEnv(identifier, name)
+	}
+}
+
+// bindEnv binds the identifier to the environment variable with the name, or
+// to the one named after the identifier if name is empty, returning an error
+// if it cannot be bound
+func (c *command) bindEnv(identifier, name string) error {
+
+	bindable := false
+	for _, a := range c.arguments {
+		if a.identifier == identifier && a.isCapture() && a.isOptional() && !a.isVariable() {
+			bindable = true
+		}
+	}
+	for _, o := range c.options {
+		if o.identifier == identifier && !o.isRepeatable() {
+			bindable = true
+		}
+	}
+	if !bindable {
+		return fmt.Errorf("only optional captures and options that are not repeatable can be bound to the environment, not %s", identifier)
+	}
+
+	if c.env == nil {
+		c.env = make(map[string]string)
+	}
+	c.env[identifier] = name
+	return nil
+
+}
+
+// envVar gets the name of the environment variable the identifier is bound
+// to, or an empty string if it is not bound
+func (c *command) envVar(identifier string) string {
+
+	name, ok := c.env[identifier]
+	switch {
+	case !ok:
+		return ""
+	case name != "":
+		return name
+	}
+
+	name = envName(identifier)
+	if c.commander != nil && c.commander.envPrefix != "" {
+		name = c.commander.envPrefix + "_" + name
+	}
+	return name
+
+}
+
+// lookupEnv gets the value of the environment variable the identifier is bound
+// to, returning the name of the variable and false if it is not set
+func (c *command) lookupEnv(identifier string) (string, string, bool) {
+
+	name := c.envVar(identifier)
+	if name == "" {
+		return "", "", false
+	}
+	value, ok := os.LookupEnv(name)
+	return value, name, ok
+
+}
+
+// envName makes the name of an environment variable from s, in upper case
+// with any characters other than letters and digits replaced by underscores
+func envName(s string) string {
+
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return '_'
+		}
+		return unicode.ToUpper(r)
+	}, s)
+
+}
@@ -0,0 +1,110 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestEnv_envName(t *testing.T) {
+
+	assert.Equal(t, envName("token"), "TOKEN")
+	assert.Equal(t, envName("my-app"), "MY_APP")
+	assert.Equal(t, envName("api.key2"), "API_KEY2")
+	assert.Equal(t, envName("naïve"), "NA_VE")
+
+}
+
+func TestEnv_envVar(t *testing.T) {
+
+	c := New()
+	c.appName = "my-app"
+	c.SetEnvPrefix(envName(c.appName))
+
+	c.Map("login [token=(string)] [--user=(string)] [--verbose]", "", "", HandlerFunc,
+		Env("token", "verbose"), EnvVar("user", "LOGIN_USER"))
+	cmd := c.commands[len(c.commands)-1]
+
+	assert.Equal(t, cmd.envVar("token"), "MY_APP_TOKEN")
+	assert.Equal(t, cmd.envVar("verbose"), "MY_APP_VERBOSE")
+	assert.Equal(t, cmd.envVar("user"), "LOGIN_USER")
+	assert.Equal(t, cmd.envVar("other"), "")
+
+	c.SetEnvPrefix("")
+	assert.Equal(t, cmd.envVar("token"), "TOKEN")
+
+	assert.Panics(t, func() {
+		c.MustMap("logout token=(string)", "", "", HandlerFunc, Env("token"))
+	})
+	assert.Panics(t, func() {
+		c.MustMap("logout [tokens=(string)...]", "", "", HandlerFunc, Env("tokens"))
+	})
+	assert.Panics(t, func() {
+		c.MustMap("logout [--tag=(string)...]", "", "", HandlerFunc, Env("tag"))
+	})
+	assert.Panics(t, func() {
+		c.MustMap("logout", "", "", HandlerFunc, Env("token"))
+	})
+
+}
+
+func TestEnv_Precedence(t *testing.T) {
+
+	c := New()
+	c.SetEnvPrefix("TESTAPP")
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+
+	var args objx.Map
+	c.Map("fetch url=(string) [retries=(int)=3] [--timeout=(duration)] [--insecure]", "Fetches", "Fetches a url.", func(a objx.Map) {
+		args = a
+	}, Env("retries", "timeout", "insecure"))
+
+	// the default
+	if assert.NoError(t, c.Run([]string{"fetch", "example.com"})) {
+		assert.Equal(t, args["retries"], int64(3))
+		assert.Nil(t, args["timeout"])
+		assert.Equal(t, args["insecure"], false)
+	}
+
+	// the environment
+	t.Setenv("TESTAPP_RETRIES", "5")
+	t.Setenv("TESTAPP_TIMEOUT", "1s")
+	t.Setenv("TESTAPP_INSECURE", "true")
+	if assert.NoError(t, c.Run([]string{"fetch", "example.com"})) {
+		assert.Equal(t, args["retries"], int64(5))
+		assert.Equal(t, args["timeout"], time.Second)
+		assert.Equal(t, args["insecure"], true)
+	}
+
+	// the command line
+	if assert.NoError(t, c.Run([]string{"fetch", "example.com", "7", "--timeout=2s"})) {
+		assert.Equal(t, args["retries"], int64(7))
+		assert.Equal(t, args["timeout"], 2*time.Second)
+	}
+
+	// an invalid value is only reported if it is used
+	t.Setenv("TESTAPP_RETRIES", "many")
+	assert.NoError(t, c.Run([]string{"fetch", "example.com", "7"}))
+
+	err := c.Run([]string{"fetch", "example.com"})
+	var mismatch *MismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Kind, MismatchInvalid)
+		assert.Equal(t, mismatch.Env, "TESTAPP_RETRIES")
+		assert.Equal(t, mismatch.Definition, "fetch url=(string) [retries=(int)=3] [--timeout=(duration)] [--insecure]")
+		assert.Equal(t, mismatch.Error(), "environment variable TESTAPP_RETRIES 'many' is not a valid int for retries: not a whole number")
+		assert.True(t, errors.Is(err, ErrUsage))
+	}
+	assert.Contains(t, stderr.String(), "\"fetch\" usage:")
+
+	assert.NoError(t, c.Run([]string{"help", "fetch"}))
+	assert.Contains(t, stdout.String(), "    retries - a valid int (env TESTAPP_RETRIES, default 3)\n")
+	assert.Contains(t, stdout.String(), "    --timeout - a valid duration (env TESTAPP_TIMEOUT)\n")
+	assert.Contains(t, stdout.String(), "    --insecure - a valid bool (env TESTAPP_INSECURE)\n")
+
+}
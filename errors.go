@@ -0,0 +1,225 @@
+package commander
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUsage is returned by Run when the arguments do not match any of the
+// mapped commands. The usage will already have been printed.
+var ErrUsage = errors.New("invalid usage")
+
+const (
+	// exitStatusError is the exit status used when a handler returns an error
+	exitStatusError int = 1
+
+	// exitStatusUsage is the exit status used when the arguments do not match
+	// any of the mapped commands
+	exitStatusUsage int = 2
+)
+
+// exitCoder is implemented by errors that carry their own exit status, such
+// as *exec.ExitError
+type exitCoder interface {
+	ExitCode() int
+}
+
+// exitStatus gets the process exit status for an error returned from Run
+func exitStatus(err error) int {
+
+	var coder exitCoder
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrUsage):
+		return exitStatusUsage
+	case errors.As(err, &coder) && coder.ExitCode() > 0:
+		return coder.ExitCode()
+	}
+
+	return exitStatusError
+
+}
+
+// MismatchKind describes the way in which arguments failed to match a command
+type MismatchKind int
+
+const (
+	// MismatchUnknown means no command could be found for the arguments
+	MismatchUnknown MismatchKind = iota
+
+	// MismatchMissing means a required argument, or the value of an option,
+	// was not given
+	MismatchMissing
+
+	// MismatchUnexpected means an argument was given that the command does not
+	// take, or an option was given more than once
+	MismatchUnexpected
+
+	// MismatchInvalid means an argument was given that is not what the command
+	// expected at that position, or cannot be converted to its capture type
+	MismatchInvalid
+
+	// MismatchAmbiguous means an argument was given that is a prefix of the
+	// literals of more than one command, when prefix matching is on
+	MismatchAmbiguous
+)
+
+// MismatchError describes why the arguments given did not match any of the
+// mapped commands. It is returned from Run, and errors.Is(err, ErrUsage) is
+// true for it.
+type MismatchError struct {
+	// Kind is the way in which the arguments failed to match
+	Kind MismatchKind
+
+	// Definition is the definition of the command that came closest to matching,
+	// or an empty string if no command came close
+	Definition string
+
+	// Position is the position of the argument that failed to match, starting
+	// from 1 for the first argument after the program name, or 0 if the failure
+	// is in a value taken from the environment or a config file
+	Position int
+
+	// Option is the name of the option that failed to match, if the failure is
+	// in an option rather than a positional argument
+	Option string
+
+	// Env is the name of the environment variable that failed to match, if
+	// the failure is in a value taken from the environment
+	Env string
+
+	// Config is the path of the config file and the line that failed to match,
+	// such as "my-app.ini:3", if the failure is in a value taken from a config
+	// file
+	Config string
+
+	// Arg is the argument that was given, if any
+	Arg string
+
+	// Identifier is the identifier of the argument that was expected, if any
+	Identifier string
+
+	// Expected describes what was expected, for example "'create'",
+	// "one of project|account" or "a valid int"
+	Expected string
+
+	// Err is the error converting the argument to its capture type, if that
+	// is why it did not match
+	Err error
+
+	// Candidates contains the literals the argument could stand for, if it is
+	// an ambiguous prefix
+	Candidates []string
+
+	// command is the command that came closest to matching
+	command *command
+
+	// argument is the positional argument that was expected, if any
+	argument *argument
+
+	// group is the group of the commands that came closest to matching, if
+	// more than one came equally close
+	group *Group
+}
+
+// Error gets a description of the mismatch, for example "argument 3 'abc' is
+// not a valid int for num: not a whole number".
+func (e *MismatchError) Error() string {
+
+	subject := fmt.Sprintf("argument %d", e.Position)
+	switch {
+	case e.Env != "":
+		subject = "environment variable " + e.Env
+	case e.Config != "":
+		subject = "setting at " + e.Config
+	case e.Option != "":
+		subject = "option " + e.Option
+	}
+
+	expected := e.Expected
+	if e.Identifier != "" {
+		expected = fmt.Sprintf("%s for %s", expected, e.Identifier)
+	}
+
+	switch e.Kind {
+	case MismatchUnknown:
+		if e.Arg == "" {
+			return "no command given"
+		}
+		return fmt.Sprintf("unknown command '%s'", e.Arg)
+	case MismatchMissing:
+		return fmt.Sprintf("%s is missing, expected %s", subject, expected)
+	case MismatchUnexpected:
+		switch {
+		case e.Option != "" && e.Arg != "":
+			return fmt.Sprintf("%s does not take a value, but was given '%s'", subject, e.Arg)
+		case e.Option != "":
+			return fmt.Sprintf("%s may only be given once", subject)
+		}
+		return fmt.Sprintf("%s '%s' is unexpected", subject, e.Arg)
+	case MismatchAmbiguous:
+		return fmt.Sprintf("%s '%s' is ambiguous, as it could be %s", subject, e.Arg, joinAlternatives(e.Candidates))
+	}
+
+	if e.Err != nil {
+		return fmt.Sprintf("%s '%s' is not %s: %s", subject, e.Arg, expected, e.Err)
+	}
+	return fmt.Sprintf("%s '%s' is not %s", subject, e.Arg, expected)
+
+}
+
+// fallbackMismatch makes a MismatchError for a value taken from the
+// environment or a config file that is not what was expected
+func fallbackMismatch(source Source, origin, value, identifier, expected string, err error) *MismatchError {
+
+	mismatch := &MismatchError{
+		Kind:       MismatchInvalid,
+		Arg:        value,
+		Identifier: identifier,
+		Expected:   expected,
+		Err:        err,
+	}
+	if source == SourceEnv {
+		mismatch.Env = origin
+	} else {
+		mismatch.Config = origin
+	}
+	return mismatch
+
+}
+
+// Unwrap gets ErrUsage, so that errors.Is(err, ErrUsage) is true for all
+// mismatches.
+func (e *MismatchError) Unwrap() error {
+
+	return ErrUsage
+
+}
+
+// DefinitionError describes a problem with the definition of a command, which
+// means it cannot be mapped. It is returned from Map.
+type DefinitionError struct {
+	// Definition is the definition of the command
+	Definition string
+
+	// Column is the column of the definition the problem is at, starting from
+	// 1 for the first character, or 0 if the problem is with the definition as
+	// a whole, such as a conflict with another command
+	Column int
+
+	// Message describes the problem
+	Message string
+}
+
+// Error gets a description of the problem, for example `definition "add
+// num=(int" column 5: the capture type is not closed with )`.
+func (e *DefinitionError) Error() string {
+
+	if e.Column == 0 {
+		return fmt.Sprintf("definition %q: %s", e.Definition, e.Message)
+	}
+	return fmt.Sprintf("definition %q column %d: %s", e.Definition, e.Column, e.Message)
+
+}
@@ -0,0 +1,29 @@
+package commander
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type exitCodeError int
+
+func (e exitCodeError) Error() string {
+	return "exit code error"
+}
+
+func (e exitCodeError) ExitCode() int {
+	return int(e)
+}
+
+func TestErrors_exitStatus(t *testing.T) {
+
+	assert.Equal(t, exitStatus(nil), 0)
+	assert.Equal(t, exitStatus(errors.New("failed")), exitStatusError)
+	assert.Equal(t, exitStatus(ErrUsage), exitStatusUsage)
+	assert.Equal(t, exitStatus(fmt.Errorf("wrapped: %w", ErrUsage)), exitStatusUsage)
+	assert.Equal(t, exitStatus(exitCodeError(42)), 42)
+	assert.Equal(t, exitStatus(exitCodeError(0)), exitStatusError)
+
+}
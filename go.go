@@ -1,7 +1,20 @@
 package commander
 
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// exit is the func used to end the process with an exit status. This exists to
+// facilitate testing.
+var exit = os.Exit
+
 // Go wraps calls to `commander.Map` (which should be placed in the func argument) and
-// initializes Commander and executes the commands.
+// initializes the shared Commander and executes the commands given on the command line.
+//
+// Programs that need more than one set of commands should use New to make a Commander
+// for each, and call its Run method instead.
 //
 // Usage
 //
@@ -12,6 +25,10 @@ package commander
 //       // make calls to commander.Map here
 //
 //     })
+//
+// If the handler returns an error, it is printed to stderr and the process exits
+// with a non-zero status. If the arguments do not match any command, the usage is
+// printed and the process exits with status 2.
 func Go(mappings func()) {
 
 	// ensure commander is initialized
@@ -21,6 +38,11 @@ func Go(mappings func()) {
 	mappings()
 
 	// execute commander
-	execute()
+	if err := execute(); err != nil {
+		if !errors.Is(err, ErrUsage) {
+			fmt.Fprintf(sharedCommander.stderr, "%s: %s\n", sharedCommander.appName, err)
+		}
+		exit(exitStatus(err))
+	}
 
 }
@@ -1,13 +1,15 @@
 package commander
 
 import (
+	"errors"
 	"github.com/stretchr/objx"
 	"github.com/stretchr/testify/assert"
+	"os"
 	"testing"
 )
 
 func TestGo(t *testing.T) {
-	sharedCommander = new(commander)
+	initialize()
 	incomingArgs = []string{}
 
 	called := false
@@ -21,14 +23,14 @@ func TestGo(t *testing.T) {
 	assert.True(t, called)
 
 	called = false
-	sharedCommander = new(commander)
+	sharedCommander = New()
 
 	Map(commandString, "", "", func(args objx.Map) {
 		called = true
 		assert.Equal(t, len(args), 3)
 		assert.Equal(t, args["kind"], "account")
 		assert.Equal(t, args["name"], "mat")
-		assert.Equal(t, args["description"], "Crazy Brit!")
+		assert.Equal(t, args["description"], []string{"Crazy Brit!"})
 	})
 
 	incomingArgs = rawCommandArrayFour
@@ -37,7 +39,7 @@ func TestGo(t *testing.T) {
 	assert.True(t, called)
 
 	called = false
-	sharedCommander = new(commander)
+	sharedCommander = New()
 
 	Map(commandStringTwoOptionalVariable, "", "", func(args objx.Map) {
 		called = true
@@ -61,3 +63,33 @@ func TestGo(t *testing.T) {
 
 	called = false
 }
+
+func TestGo_ExitStatus(t *testing.T) {
+
+	status := 0
+	exit = func(code int) {
+		status = code
+	}
+	defer func() {
+		exit = os.Exit
+	}()
+
+	sharedCommander = New()
+	incomingArgs = []string{"fail"}
+
+	Go(func() {
+		MapE("fail", "", "", func(args objx.Map) error {
+			return errors.New("failed")
+		})
+	})
+
+	assert.Equal(t, status, exitStatusError)
+
+	status = 0
+	incomingArgs = []string{"unknown"}
+
+	Go(func() {})
+
+	assert.Equal(t, status, exitStatusUsage)
+
+}
@@ -0,0 +1,290 @@
+package commander
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/commander/syntax"
+	"io"
+	"strings"
+)
+
+// Group is a named group of commands, such as "user" for the "user create"
+// and "user delete" commands. Groups have their own summary and help, so
+// "help user" lists only the user commands, and may be nested to any depth.
+type Group struct {
+	// commander is the Commander the group belongs to
+	commander *Commander
+
+	// parent is the group this group is nested in, or nil if it is at the top
+	parent *Group
+
+	// name is the literal that starts the commands in this group
+	name string
+
+	// summary is a string containing a short summary of this group
+	summary string
+
+	// groups contains the groups nested in this group
+	groups []*Group
+
+	// err describes why the name of this group (or a group it is nested in)
+	// is not valid, or is nil if it is
+	err error
+}
+
+// Group gets the group of commands with the given name, making it if it has not
+// been made already. Commands mapped on the group are invoked by the name of
+// the group followed by their definition.
+func (c *Commander) Group(name, summary string) *Group {
+
+	return c.group(nil, name, summary)
+
+}
+
+// Group gets the group of commands with the given name nested in this group,
+// making it if it has not been made already.
+func (g *Group) Group(name, summary string) *Group {
+
+	return g.commander.group(g, name, summary)
+
+}
+
+// group gets the group with the name in the parent group (or at the top if
+// parent is nil), making it if it has not been made already
+func (c *Commander) group(parent *Group, name, summary string) *Group {
+
+	groups := c.groups
+	if parent != nil {
+		groups = parent.groups
+	}
+
+	for _, g := range groups {
+		if g.name == name {
+			if summary != "" {
+				g.summary = summary
+			}
+			return g
+		}
+	}
+
+	g := &Group{commander: c, parent: parent, name: name, summary: summary}
+	if parent != nil && parent.err != nil {
+		g.err = parent.err
+	} else {
+		g.err = checkGroupName(name)
+	}
+	if parent != nil {
+		parent.groups = append(parent.groups, g)
+	} else {
+		c.groups = append(c.groups, g)
+	}
+
+	return g
+
+}
+
+// checkGroupName ensures the name of a group is a single literal, returning an
+// error describing why not if it is not
+func checkGroupName(name string) error {
+
+	parsed, err := syntax.Parse(name)
+	if err == nil && len(parsed.Nodes) == 1 {
+		if literal, ok := parsed.Nodes[0].(*syntax.Literal); ok && literal.Value == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("the group name %q is not valid, as it must be a single literal such as user", name)
+
+}
+
+// Map is used to map a definition string to a handler function in this group.
+// The definition is given without the name of the group, so mapping "create
+// name=(string)" on the "user" group is invoked by "user create mat".
+//
+// Mapping DefaultCommand on a group maps the handler that is called when the
+// group is invoked with no further arguments.
+//
+// Map returns a *DefinitionError if the command cannot be mapped, or the name
+// of the group is not a single literal, in which case the error is also
+// returned from Run. The columns of errors count from the start of the
+// definition as it was given, without the name of the group. See
+// Commander.Map.
+func (g *Group) Map(definition, summary, description string, handler Handler, opts ...MapOption) error {
+
+	return g.commander.keepError(g.mapHandler(definition, summary, description, handler, opts))
+
+}
+
+// MapE is used to map a definition string to a handler function that can fail
+// in this group.
+//
+// See Group.Map.
+func (g *Group) MapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) error {
+
+	return g.commander.keepError(g.mapErrorHandler(definition, summary, description, handler, opts))
+
+}
+
+// MustMap is like Map, but panics if the command cannot be mapped.
+func (g *Group) MustMap(definition, summary, description string, handler Handler, opts ...MapOption) {
+
+	if err := g.mapHandler(definition, summary, description, handler, opts); err != nil {
+		panic(err)
+	}
+
+}
+
+// MustMapE is like MapE, but panics if the command cannot be mapped.
+func (g *Group) MustMapE(definition, summary, description string, handler ErrorHandler, opts ...MapOption) {
+
+	if err := g.mapErrorHandler(definition, summary, description, handler, opts); err != nil {
+		panic(err)
+	}
+
+}
+
+// mapHandler makes a command in this group with the handler and maps it
+func (g *Group) mapHandler(definition, summary, description string, handler Handler, opts []MapOption) error {
+
+	if g.err != nil {
+		return &DefinitionError{Definition: definition, Message: g.err.Error()}
+	}
+	cmd, err := makeCommand(g.definition(definition), summary, description, handler, opts...)
+	if err != nil {
+		return g.localError(definition, err)
+	}
+	return g.localError(definition, g.mapCommand(cmd))
+
+}
+
+// mapErrorHandler makes a command in this group with the handler that can
+// fail and maps it
+func (g *Group) mapErrorHandler(definition, summary, description string, handler ErrorHandler, opts []MapOption) error {
+
+	if g.err != nil {
+		return &DefinitionError{Definition: definition, Message: g.err.Error()}
+	}
+	cmd, err := makeCommandE(g.definition(definition), summary, description, handler, opts...)
+	if err != nil {
+		return g.localError(definition, err)
+	}
+	return g.localError(definition, g.mapCommand(cmd))
+
+}
+
+// localError makes a *DefinitionError for the full definition of a command in
+// this group refer to the definition as it was given, with its column counted
+// from the start of that definition, and returns other errors unchanged
+func (g *Group) localError(definition string, err error) error {
+
+	var definitionErr *DefinitionError
+	if definition == DefaultCommand || !errors.As(err, &definitionErr) {
+		return err
+	}
+	full := g.definition(definition)
+	if definitionErr.Definition != full {
+		return err
+	}
+
+	local := *definitionErr
+	local.Definition = definition
+	if local.Column > 0 {
+		local.Column -= len(full) - len(definition)
+	}
+	return &local
+
+}
+
+// mapCommand adds a command to the commander the group belongs to
+func (g *Group) mapCommand(cmd *command) error {
+
+	cmd.group = g
+	return g.commander.mapCommand(cmd)
+
+}
+
+// path gets the names of this group and the groups it is nested in, from the
+// top down
+func (g *Group) path() []string {
+
+	if g.parent == nil {
+		return []string{g.name}
+	}
+	return append(g.parent.path(), g.name)
+
+}
+
+// definition gets the full definition of a command in this group
+func (g *Group) definition(definition string) string {
+
+	if definition == DefaultCommand {
+		return strings.Join(g.path(), delimiterArgumentSeparator)
+	}
+	return strings.Join(append(g.path(), definition), delimiterArgumentSeparator)
+
+}
+
+// findGroup gets the group with the path given by the words, or nil if there
+// is none
+func (c *Commander) findGroup(words []string) *Group {
+
+	var group *Group
+	groups := c.groups
+
+	for _, word := range words {
+		group = nil
+		for _, g := range groups {
+			if g.name == word {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			return nil
+		}
+		groups = group.groups
+	}
+
+	return group
+
+}
+
+// printCommands prints a line for each of the commands directly in the group
+// (or at the top if group is nil), and a line for each of the groups nested
+// directly in it, in the order they were mapped
+func (c *Commander) printCommands(w io.Writer, group *Group) {
+
+	printed := make(map[*Group]bool)
+
+	for _, cmd := range c.commands {
+		if cmd.isDefaultCommand() || cmd.hidden {
+			continue
+		}
+		if cmd.group == group {
+			fmt.Fprintf(w, "    %s - %s\n", cmd.title(), cmd.summary)
+			continue
+		}
+		for g := cmd.group; g != nil; g = g.parent {
+			if g.parent == group {
+				if !printed[g] {
+					printed[g] = true
+					fmt.Fprintf(w, "    %s <command> - %s\n", strings.Join(g.path(), delimiterArgumentSeparator), g.summary)
+				}
+				break
+			}
+		}
+	}
+
+}
+
+// printGroupUsage prints the usage of the commands in the group
+func (c *Commander) printGroupUsage(w io.Writer, group *Group) {
+
+	fmt.Fprintf(w, "\n\"%s\" commands:\n\n", strings.Join(group.path(), delimiterArgumentSeparator))
+	if group.summary != "" {
+		fmt.Fprintf(w, "    %s\n\n", group.summary)
+	}
+	c.printCommands(w, group)
+	fmt.Fprintln(w)
+
+}
@@ -0,0 +1,137 @@
+package commander
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func makeGroupCommander(calls *[]string) (*Commander, *bytes.Buffer, *bytes.Buffer) {
+
+	c := New()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+
+	handler := func(name string) Handler {
+		return func(args objx.Map) {
+			*calls = append(*calls, name)
+		}
+	}
+
+	c.Map("version", "Prints the version", "", handler("version"))
+
+	user := c.Group("user", "Manages users")
+	user.Map(DefaultCommand, "Prints the current user", "", handler("user"))
+	user.Map("create name=(string)", "Creates a user", "", handler("user create"))
+	user.Map("delete name=(string)", "Deletes a user", "", handler("user delete"))
+
+	role := user.Group("role", "Manages the roles of users")
+	role.Map("grant name=(string) role=(string)", "Grants a role", "", handler("user role grant"))
+
+	project := c.Group("project", "Manages projects")
+	project.Map("create name=(string)", "Creates a project", "", handler("project create"))
+
+	return c, stdout, stderr
+
+}
+
+func TestGroup_Map(t *testing.T) {
+
+	var calls []string
+	c, _, _ := makeGroupCommander(&calls)
+
+	assert.NoError(t, c.Run([]string{"user"}))
+	assert.NoError(t, c.Run([]string{"user", "create", "mat"}))
+	assert.NoError(t, c.Run([]string{"user", "role", "grant", "mat", "admin"}))
+	assert.NoError(t, c.Run([]string{"project", "create", "commander"}))
+	assert.Equal(t, calls, []string{"user", "user create", "user role grant", "project create"})
+
+	// the same group is got by name
+	assert.Equal(t, c.Group("user", ""), c.Group("user", "Manages users"))
+	assert.Equal(t, c.Group("user", "").summary, "Manages users")
+	assert.Equal(t, len(c.groups), 2)
+
+	if assert.Equal(t, c.findGroup([]string{"user", "role"}).path(), []string{"user", "role"}) {
+		assert.Nil(t, c.findGroup([]string{"role"}))
+		assert.Nil(t, c.findGroup([]string{"user", "unknown"}))
+	}
+
+}
+
+func TestGroup_Help(t *testing.T) {
+
+	var calls []string
+	c, stdout, _ := makeGroupCommander(&calls)
+
+	assert.NoError(t, c.Run([]string{"help"}))
+	assert.Contains(t, stdout.String(), "version - Prints the version")
+	assert.Contains(t, stdout.String(), "user <command> - Manages users")
+	assert.Contains(t, stdout.String(), "project <command> - Manages projects")
+	assert.NotContains(t, stdout.String(), "user create")
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help", "user"}))
+	assert.Contains(t, stdout.String(), "\"user\" commands:")
+	assert.Contains(t, stdout.String(), "    user - Prints the current user")
+	assert.Contains(t, stdout.String(), "    user create name=(string) - Creates a user")
+	assert.Contains(t, stdout.String(), "    user role <command> - Manages the roles of users")
+	assert.NotContains(t, stdout.String(), "grant")
+	assert.NotContains(t, stdout.String(), "project")
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help", "user", "role"}))
+	assert.Contains(t, stdout.String(), "user role grant name=(string) role=(string) - Grants a role")
+
+	stdout.Reset()
+	assert.NoError(t, c.Run([]string{"help", "user", "create"}))
+	assert.Contains(t, stdout.String(), "\"user create\" usage:")
+	assert.NotContains(t, stdout.String(), "user delete")
+
+}
+
+func TestGroup_Mismatch(t *testing.T) {
+
+	var calls []string
+	c, _, stderr := makeGroupCommander(&calls)
+
+	err := c.Run([]string{"user", "frob"})
+	if mismatch, ok := err.(*MismatchError); assert.True(t, ok) {
+		assert.Equal(t, mismatch.Error(), "argument 2 'frob' is not one of create|delete|role")
+		assert.Contains(t, stderr.String(), "\"user\" commands:")
+	}
+
+	stderr.Reset()
+	err = c.Run([]string{"project"})
+	if mismatch, ok := err.(*MismatchError); assert.True(t, ok) {
+		assert.Equal(t, mismatch.Error(), "argument 2 is missing, expected 'create'")
+		assert.Contains(t, stderr.String(), "\"project create\" usage:")
+	}
+
+	assert.Empty(t, calls)
+
+}
+
+func TestGroup_Errors(t *testing.T) {
+
+	c := New()
+
+	// the columns of errors count from the start of the definition as given
+	err := c.Group("user", "").Group("role", "").Map("add name=(string", "", "", HandlerFunc)
+	assert.Equal(t, err, &DefinitionError{"add name=(string", 10, "the capture type is not closed with )"})
+
+	err = c.Group("user", "").Map("list [n=(int)=many]", "", "", HandlerFunc)
+	assert.Equal(t, err, &DefinitionError{"list [n=(int)=many]", 7, "the default many of n is not a valid int: not a whole number"})
+
+	// group names must be single literals
+	for _, name := range []string{"two words", "", "-x", "a|b", "a=(int)", `"quoted"`} {
+		err = c.Group(name, "").Map("create", "", "", HandlerFunc)
+		assert.Equal(t, err, &DefinitionError{Definition: "create", Message: fmt.Sprintf("the group name %q is not valid, as it must be a single literal such as user", name)}, name)
+	}
+	err = c.Group("a b", "").Group("c", "").Map("create", "", "", HandlerFunc)
+	assert.Error(t, err)
+	assert.Error(t, c.Run([]string{"help"}))
+
+}
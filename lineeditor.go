@@ -0,0 +1,629 @@
+package commander
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// errInterrupt is returned by the line editor when Ctrl-C is pressed
+var errInterrupt = errors.New("interrupted")
+
+// defaultHistorySize is the number of lines of console history kept unless
+// SetHistorySize is called
+const defaultHistorySize int = 500
+
+// keys the line editor understands, as read from a terminal in raw mode
+const (
+	keyCtrlA     rune = 1
+	keyCtrlB     rune = 2
+	keyCtrlC     rune = 3
+	keyCtrlD     rune = 4
+	keyCtrlE     rune = 5
+	keyCtrlF     rune = 6
+	keyCtrlG     rune = 7
+	keyCtrlH     rune = 8
+	keyTab       rune = 9
+	keyLineFeed  rune = 10
+	keyCtrlK     rune = 11
+	keyCtrlL     rune = 12
+	keyEnter     rune = 13
+	keyCtrlN     rune = 14
+	keyCtrlP     rune = 16
+	keyCtrlR     rune = 18
+	keyCtrlU     rune = 21
+	keyCtrlW     rune = 23
+	keyEscape    rune = 27
+	keyBackspace rune = 127
+)
+
+// keys the line editor understands that are read from a terminal as escape
+// sequences. These are negative so that they never clash with a real rune.
+const (
+	keyUnknown rune = -(iota + 1)
+	keyUp
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyWordLeft
+	keyWordRight
+)
+
+// history holds the lines entered into the console, oldest first
+type history struct {
+	// entries contains the lines in the history
+	entries []string
+
+	// size is the maximum number of entries to keep
+	size int
+}
+
+// add adds the line to the history, unless it is blank or the same as the
+// most recent entry, and drops the oldest entries beyond the size
+func (h *history) add(line string) {
+
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return
+	}
+
+	h.entries = append(h.entries, line)
+	h.trim()
+
+}
+
+// trim drops the oldest entries beyond the size
+func (h *history) trim() {
+
+	if len(h.entries) > h.size {
+		if h.size <= 0 {
+			h.entries = nil
+		} else {
+			h.entries = h.entries[len(h.entries)-h.size:]
+		}
+	}
+
+}
+
+// load reads the history from the file at path, one entry per line. A file
+// that does not exist yet is not an error.
+func (h *history) load(path string) error {
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h.entries = nil
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.add(scanner.Text())
+	}
+
+	return scanner.Err()
+
+}
+
+// save writes the history to the file at path, one entry per line
+func (h *history) save(path string) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	var contents string
+	if len(h.entries) > 0 {
+		contents = strings.Join(h.entries, "\n") + "\n"
+	}
+
+	return os.WriteFile(path, []byte(contents), 0600)
+
+}
+
+// defaultHistoryFile gets the path of the history file for the app, in the
+// home directory of the user, or an empty string if there is no home
+// directory
+func defaultHistoryFile(appName string) string {
+
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, "."+appName+"_history")
+
+}
+
+// lineEditor reads lines typed at a terminal in raw mode, providing cursor
+// movement, editing keys, history recall and reverse history search.
+type lineEditor struct {
+	// in is the terminal input
+	in *bufio.Reader
+
+	// out is the terminal output
+	out io.Writer
+
+	// history holds the lines entered so far
+	history *history
+
+	// prompt is the prompt for the line being read
+	prompt string
+
+	// buf holds the line being edited
+	buf []rune
+
+	// pos is the position of the cursor in buf
+	pos int
+
+	// historyIndex is the index in the history of the line being edited, or
+	// the number of entries if it is a new line
+	historyIndex int
+
+	// pending holds the new line being edited while the user looks through
+	// the history
+	pending []rune
+
+	// completer gets the candidates for the last of the words when Tab is
+	// pressed, given the words before it. If it is nil, Tab does nothing.
+	completer func(words []string) []string
+}
+
+// newLineEditor makes a new lineEditor reading from in and writing to out
+func newLineEditor(in *bufio.Reader, out io.Writer, h *history) *lineEditor {
+
+	return &lineEditor{in: in, out: out, history: h}
+
+}
+
+// readKey reads a single key from the input, decoding escape sequences
+func (e *lineEditor) readKey() (rune, error) {
+
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != keyEscape {
+		return r, err
+	}
+
+	r, _, err = e.in.ReadRune()
+	if err != nil {
+		return keyUnknown, err
+	}
+
+	switch r {
+	case 'b':
+		return keyWordLeft, nil
+	case 'f':
+		return keyWordRight, nil
+	case '[', 'O':
+	default:
+		return keyUnknown, nil
+	}
+
+	// read the parameters and final byte of the sequence, such as "3~"
+	var sequence []rune
+	for {
+		b, _, err := e.in.ReadRune()
+		if err != nil {
+			return keyUnknown, err
+		}
+		sequence = append(sequence, b)
+		if b >= 0x40 && b <= 0x7e {
+			break
+		}
+	}
+
+	switch string(sequence) {
+	case "A":
+		return keyUp, nil
+	case "B":
+		return keyDown, nil
+	case "C":
+		return keyRight, nil
+	case "D":
+		return keyLeft, nil
+	case "H", "1~", "7~":
+		return keyHome, nil
+	case "F", "4~", "8~":
+		return keyEnd, nil
+	case "3~":
+		return keyDelete, nil
+	case "1;5D", "1;3D":
+		return keyWordLeft, nil
+	case "1;5C", "1;3C":
+		return keyWordRight, nil
+	}
+
+	return keyUnknown, nil
+
+}
+
+// refresh redraws the prompt and the line, and puts the cursor in place
+func (e *lineEditor) refresh() {
+
+	e.draw(e.prompt, e.buf, e.pos)
+
+}
+
+// draw redraws the current terminal line with the prompt and line, putting the
+// cursor at pos in the line
+func (e *lineEditor) draw(prompt string, line []rune, pos int) {
+
+	fmt.Fprintf(e.out, "\r%s%s\x1b[K", prompt, string(line))
+	if back := len(line) - pos; back > 0 {
+		fmt.Fprintf(e.out, "\x1b[%dD", back)
+	}
+
+}
+
+// setLine replaces the line being edited, putting the cursor at the end
+func (e *lineEditor) setLine(line []rune) {
+
+	e.buf = append(e.buf[:0], line...)
+	e.pos = len(e.buf)
+
+}
+
+// insert inserts the rune at the cursor
+func (e *lineEditor) insert(r rune) {
+
+	e.buf = append(e.buf, 0)
+	copy(e.buf[e.pos+1:], e.buf[e.pos:])
+	e.buf[e.pos] = r
+	e.pos++
+
+}
+
+// deleteRange deletes the runes in the line from start up to end, moving the
+// cursor to start
+func (e *lineEditor) deleteRange(start, end int) {
+
+	e.buf = append(e.buf[:start], e.buf[end:]...)
+	e.pos = start
+
+}
+
+// wordStart gets the position of the start of the word before the cursor
+func (e *lineEditor) wordStart() int {
+
+	pos := e.pos
+	for pos > 0 && unicode.IsSpace(e.buf[pos-1]) {
+		pos--
+	}
+	for pos > 0 && !unicode.IsSpace(e.buf[pos-1]) {
+		pos--
+	}
+	return pos
+
+}
+
+// wordEnd gets the position of the end of the word after the cursor
+func (e *lineEditor) wordEnd() int {
+
+	pos := e.pos
+	for pos < len(e.buf) && unicode.IsSpace(e.buf[pos]) {
+		pos++
+	}
+	for pos < len(e.buf) && !unicode.IsSpace(e.buf[pos]) {
+		pos++
+	}
+	return pos
+
+}
+
+// historyMove moves through the history by delta entries, keeping the new line
+// being edited so it can be returned to
+func (e *lineEditor) historyMove(delta int) {
+
+	index := e.historyIndex + delta
+	if index < 0 || index > len(e.history.entries) {
+		return
+	}
+
+	if e.historyIndex == len(e.history.entries) {
+		e.pending = append(e.pending[:0], e.buf...)
+	}
+
+	e.historyIndex = index
+	if index == len(e.history.entries) {
+		e.setLine(e.pending)
+	} else {
+		e.setLine([]rune(e.history.entries[index]))
+	}
+
+}
+
+// readLine reads a line, showing the prompt and handling the editing keys
+// until enter is pressed. The line is added to the history. io.EOF is
+// returned if Ctrl-D is pressed on an empty line, and errInterrupt if
+// Ctrl-C is pressed.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+
+	e.prompt = prompt
+	e.buf = e.buf[:0]
+	e.pos = 0
+	e.historyIndex = len(e.history.entries)
+	e.pending = e.pending[:0]
+
+	e.refresh()
+
+	for {
+
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		switch key {
+		case keyEnter, keyLineFeed:
+			return e.submit(), nil
+		case keyCtrlC:
+			return "", e.interrupt()
+		case keyCtrlD:
+			if len(e.buf) == 0 {
+				fmt.Fprint(e.out, "\r\n")
+				return "", io.EOF
+			}
+			if e.pos < len(e.buf) {
+				e.deleteRange(e.pos, e.pos+1)
+			}
+		case keyDelete:
+			if e.pos < len(e.buf) {
+				e.deleteRange(e.pos, e.pos+1)
+			}
+		case keyBackspace, keyCtrlH:
+			if e.pos > 0 {
+				e.deleteRange(e.pos-1, e.pos)
+			}
+		case keyLeft, keyCtrlB:
+			if e.pos > 0 {
+				e.pos--
+			}
+		case keyRight, keyCtrlF:
+			if e.pos < len(e.buf) {
+				e.pos++
+			}
+		case keyHome, keyCtrlA:
+			e.pos = 0
+		case keyEnd, keyCtrlE:
+			e.pos = len(e.buf)
+		case keyWordLeft:
+			e.pos = e.wordStart()
+		case keyWordRight:
+			e.pos = e.wordEnd()
+		case keyUp, keyCtrlP:
+			e.historyMove(-1)
+		case keyDown, keyCtrlN:
+			e.historyMove(1)
+		case keyCtrlK:
+			e.buf = e.buf[:e.pos]
+		case keyCtrlU:
+			e.deleteRange(0, e.pos)
+		case keyCtrlW:
+			e.deleteRange(e.wordStart(), e.pos)
+		case keyCtrlL:
+			fmt.Fprint(e.out, "\x1b[H\x1b[2J")
+		case keyTab:
+			e.completeWord()
+		case keyCtrlR:
+			submit, err := e.reverseSearch()
+			if err != nil {
+				return "", err
+			}
+			if submit {
+				return e.submit(), nil
+			}
+		default:
+			if key > 0 && unicode.IsPrint(key) {
+				e.insert(key)
+			}
+		}
+
+		e.refresh()
+
+	}
+
+}
+
+// completeWord completes the word before the cursor using the completer. A
+// single candidate is completed in full, several candidates are completed
+// as far as they agree, and if that adds nothing they are listed below the
+// line. Whitespace and quotes in what is completed are escaped, so that the
+// console reads it back as one argument.
+func (e *lineEditor) completeWord() {
+
+	if e.completer == nil {
+		return
+	}
+
+	words, starts, inWord, err := scanLine(e.buf[:e.pos])
+	if err != nil {
+		return
+	}
+	start := e.pos
+	if inWord {
+		start = starts[len(starts)-1]
+	} else {
+		words = append(words, "")
+	}
+	current := words[len(words)-1]
+
+	candidates := e.completer(words)
+
+	switch len(candidates) {
+	case 0:
+		fmt.Fprint(e.out, "\a")
+		return
+	case 1:
+		if e.pos < len(e.buf) && unicode.IsSpace(e.buf[e.pos]) {
+			e.replaceWord(start, escapeArg(candidates[0]))
+		} else {
+			e.replaceWord(start, escapeArg(candidates[0])+" ")
+		}
+		return
+	}
+
+	prefix := commonPrefix(candidates)
+	if utf8.RuneCountInString(prefix) > utf8.RuneCountInString(current) {
+		e.replaceWord(start, escapeArg(prefix))
+		return
+	}
+
+	fmt.Fprintf(e.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+
+}
+
+// replaceWord replaces the text from start up to the cursor with the word,
+// leaving the cursor after it
+func (e *lineEditor) replaceWord(start int, word string) {
+
+	rest := append([]rune(word), e.buf[e.pos:]...)
+	e.buf = append(e.buf[:start], rest...)
+	e.pos = start + len([]rune(word))
+
+}
+
+// commonPrefix gets the longest prefix shared by all of the strings
+func commonPrefix(strs []string) string {
+
+	if len(strs) == 0 {
+		return ""
+	}
+
+	prefix := []rune(strs[0])
+	for _, str := range strs[1:] {
+		for !strings.HasPrefix(str, string(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return string(prefix)
+
+}
+
+// interrupt abandons the line being edited, returning errInterrupt
+func (e *lineEditor) interrupt() error {
+
+	e.pos = len(e.buf)
+	e.refresh()
+	fmt.Fprint(e.out, "^C\r\n")
+	return errInterrupt
+
+}
+
+// submit finishes the line being edited, adding it to the history
+func (e *lineEditor) submit() string {
+
+	e.pos = len(e.buf)
+	e.refresh()
+	fmt.Fprint(e.out, "\r\n")
+
+	line := string(e.buf)
+	e.history.add(line)
+	return line
+
+}
+
+// searchHistory gets the index of the newest history entry at or before from
+// that contains the query, or -1 if there is none
+func (e *lineEditor) searchHistory(query string, from int) int {
+
+	if from >= len(e.history.entries) {
+		from = len(e.history.entries) - 1
+	}
+	for i := from; i >= 0; i-- {
+		if strings.Contains(e.history.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+
+}
+
+// reverseSearch searches backwards through the history for the text typed,
+// as Ctrl-R does in a shell. Pressing Ctrl-R again finds the next older
+// match, enter submits the match, Ctrl-G cancels the search, and any other
+// editing key keeps the match for further editing. It returns true if the
+// line should be submitted.
+func (e *lineEditor) reverseSearch() (bool, error) {
+
+	original := append([]rune(nil), e.buf...)
+	originalPos := e.pos
+
+	var query []rune
+	match := -1
+	failed := false
+
+	for {
+
+		var found []rune
+		if match >= 0 {
+			found = []rune(e.history.entries[match])
+		}
+		label := "(reverse-i-search)"
+		if failed {
+			label = "(failed reverse-i-search)"
+		}
+		e.draw(fmt.Sprintf("%s`%s': ", label, string(query)), found, len(found))
+
+		key, err := e.readKey()
+		if err != nil {
+			return false, err
+		}
+
+		switch key {
+		case keyCtrlR:
+			if match > 0 {
+				if next := e.searchHistory(string(query), match-1); next >= 0 {
+					match = next
+				}
+			}
+			continue
+		case keyBackspace, keyCtrlH:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				match = e.searchHistory(string(query), len(e.history.entries))
+				failed = match < 0
+			}
+			continue
+		case keyCtrlC:
+			return false, e.interrupt()
+		case keyCtrlG:
+			e.buf = append(e.buf[:0], original...)
+			e.pos = originalPos
+			return false, nil
+		}
+
+		if key > 0 && unicode.IsPrint(key) {
+			query = append(query, key)
+			from := match
+			if from < 0 {
+				from = len(e.history.entries)
+			}
+			if next := e.searchHistory(string(query), from); next >= 0 {
+				match = next
+				failed = false
+			} else {
+				failed = true
+			}
+			continue
+		}
+
+		if match >= 0 {
+			e.setLine(found)
+		}
+		return key == keyEnter || key == keyLineFeed, nil
+
+	}
+
+}
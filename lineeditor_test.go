@@ -0,0 +1,203 @@
+package commander
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readEditorLines feeds the keys to a line editor, returning the lines read
+// and the error that ended the reading
+func readEditorLines(h *history, keys string) ([]string, error) {
+
+	editor := newLineEditor(bufio.NewReader(strings.NewReader(keys)), new(bytes.Buffer), h)
+
+	var lines []string
+	for {
+		line, err := editor.readLine("> ")
+		if err != nil {
+			return lines, err
+		}
+		lines = append(lines, line)
+	}
+
+}
+
+func TestLineEditor_readLine(t *testing.T) {
+
+	for _, test := range []struct {
+		keys string
+		line string
+	}{
+		{"create\r", "create"},
+		{"create\n", "create"},
+		{"creat\x7f\x7fate\r", "create"},
+		{"reate\x01c\r", "create"},
+		{"crate\x1b[D\x1b[D\x1b[De\r", "create"},
+		{"cxreate\x01\x06\x1b[3~\r", "create"},
+		{"cxreate\x01\x06\x04\r", "create"},
+		{"create project\x17\r", "create "},
+		{"create project\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x1b[C\x0b\r", "create"},
+		{"oops create\x1bb\x15\r", "create"},
+		{"create \x1b[H\x1b[F project\r", "create  project"},
+		{"create\x1b[Z\r", "create"},
+	} {
+		lines, err := readEditorLines(&history{size: 10}, test.keys)
+		assert.Equal(t, err, io.EOF, "%q", test.keys)
+		assert.Equal(t, lines, []string{test.line}, "%q", test.keys)
+	}
+
+	lines, err := readEditorLines(&history{size: 10}, "create\r\x04")
+	assert.Equal(t, err, io.EOF)
+	assert.Equal(t, lines, []string{"create"})
+
+	lines, err = readEditorLines(&history{size: 10}, "create\x03")
+	assert.Equal(t, err, errInterrupt)
+	assert.Empty(t, lines)
+
+	lines, err = readEditorLines(&history{size: 10, entries: []string{"create"}}, "\x12cr\x03")
+	assert.Equal(t, err, errInterrupt)
+	assert.Empty(t, lines)
+
+}
+
+func TestLineEditor_History(t *testing.T) {
+
+	h := &history{size: 10}
+
+	lines, _ := readEditorLines(h, "one\rtwo\r\x1b[A\x1b[A\r\x1b[A\x1b[B\r\x10 three\r")
+	assert.Equal(t, lines, []string{"one", "two", "one", "", "one three"})
+	assert.Equal(t, h.entries, []string{"one", "two", "one", "one three"})
+
+	// a new line being edited is kept while looking through the history
+	h = &history{size: 10, entries: []string{"one"}}
+	lines, _ = readEditorLines(h, "tw\x1b[A\x1b[Bo\r")
+	assert.Equal(t, lines, []string{"two"})
+
+}
+
+func TestLineEditor_reverseSearch(t *testing.T) {
+
+	entries := []string{"create project one", "delete project one", "create account two"}
+
+	for _, test := range []struct {
+		keys string
+		line string
+	}{
+		{"\x12create\r", "create account two"},
+		{"\x12create\x12\r", "create project one"},
+		{"\x12project\r", "delete project one"},
+		{"\x12crx\x7feate\x12\r", "create project one"},
+		{"\x12delete\x1b[C!\r", "delete project one!"},
+		{"keep\x12delete\x07\r", "keep"},
+		{"\x12missing\r", ""},
+	} {
+		lines, _ := readEditorLines(&history{size: 10, entries: entries}, test.keys)
+		assert.Equal(t, lines, []string{test.line}, "%q", test.keys)
+	}
+
+}
+
+func TestHistory(t *testing.T) {
+
+	h := &history{size: 3}
+
+	h.add("one")
+	h.add("one")
+	h.add("  ")
+	h.add("two")
+	h.add("three")
+	h.add("four")
+
+	assert.Equal(t, h.entries, []string{"two", "three", "four"})
+
+	path := filepath.Join(t.TempDir(), "history")
+
+	assert.NoError(t, h.load(path))
+	assert.NoError(t, h.save(path))
+
+	contents, err := os.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.Equal(t, string(contents), "two\nthree\nfour\n")
+	}
+
+	loaded := &history{size: 2}
+	if assert.NoError(t, loaded.load(path)) {
+		assert.Equal(t, loaded.entries, []string{"three", "four"})
+	}
+
+	none := &history{size: 0}
+	none.add("one")
+	assert.Empty(t, none.entries)
+
+	assert.Equal(t, defaultHistoryFile("myapp") != "", true)
+	assert.True(t, strings.HasSuffix(defaultHistoryFile("myapp"), ".myapp_history"))
+
+}
+
+func TestLineEditor_completeWord(t *testing.T) {
+
+	c := makeCompletionCommander()
+
+	for _, test := range []struct {
+		keys string
+		line string
+	}{
+		{"cr\t\r", "create "},
+		{"create p\t\r", "create project "},
+		{"create \t\r", "create "},
+		{"de\tl\t\r", "delete "},
+		{"de\t\t\r", "de"},
+		{"create x\t\r", "create x"},
+		{"e\t\r", "exit "},
+		{"cr project\x01\x06\x06\t\r", "create project"},
+		{"create \"p\t\r", "create \"p"},
+	} {
+		editor := newLineEditor(bufio.NewReader(strings.NewReader(test.keys)), new(bytes.Buffer), &history{})
+		editor.completer = c.completeConsole
+		line, err := editor.readLine("> ")
+		if assert.NoError(t, err, "%q", test.keys) {
+			assert.Equal(t, line, test.line, "%q", test.keys)
+		}
+	}
+
+	// candidates with whitespace or quotes are escaped
+	c.Map(`pick item="a b"|"a c"|"it's"`, "", "", func(objx.Map) {
+	})
+	for _, test := range []struct {
+		keys string
+		line string
+	}{
+		{"pick a\t\r", `pick a\ `},
+		{"pick a\tb\t\r", `pick a\ b `},
+		{"pick 'a b\t\r", `pick 'a b`},
+		{"pick i\t\r", `pick it\'s `},
+	} {
+		editor := newLineEditor(bufio.NewReader(strings.NewReader(test.keys)), new(bytes.Buffer), &history{})
+		editor.completer = c.completeConsole
+		line, err := editor.readLine("> ")
+		if assert.NoError(t, err, "%q", test.keys) {
+			assert.Equal(t, line, test.line, "%q", test.keys)
+		}
+	}
+
+	// several candidates are listed
+	out := new(bytes.Buffer)
+	editor := newLineEditor(bufio.NewReader(strings.NewReader("de\t\r")), out, &history{})
+	editor.completer = c.completeConsole
+	editor.readLine("> ")
+	assert.Contains(t, out.String(), "delete  deploy")
+
+	assert.Equal(t, commonPrefix([]string{"delete", "deploy"}), "de")
+	assert.Equal(t, commonPrefix([]string{"create"}), "create")
+	assert.Equal(t, commonPrefix([]string{"a", "b"}), "")
+	assert.Equal(t, commonPrefix([]string{"é", "è"}), "")
+	assert.Equal(t, commonPrefix([]string{"café", "cafè"}), "caf")
+
+}
@@ -0,0 +1,188 @@
+package commander
+
+import (
+	"github.com/stretchr/commander/syntax"
+	"strings"
+)
+
+// option is a named flag in a command definition, such as "--force", "-v" or
+// "--timeout=(int)". Options are always optional, and may appear anywhere in
+// the arguments.
+type option struct {
+	// rawArg is a string containing the option in its raw form
+	rawArg string
+
+	// long is the long form of the option, without the leading dashes
+	long string
+
+	// short is the short form of the option, without the leading dash
+	short string
+
+	// identifier is a string containing the identifier of the option
+	identifier string
+
+	// captureType is a string containing the type of the option's value. Options
+	// without a capture type are switches.
+	captureType string
+
+	// constraint restricts the values the option accepts, or is nil if it
+	// accepts any value of the capture type
+	constraint *constraint
+
+	// repeatable is a bool used to determine if this option may be given more
+	// than once
+	repeatable bool
+
+	// defaultValue is the value used for the option if it is not given, or an
+	// empty string if there is none
+	defaultValue string
+
+	// column is the column of the definition the option starts at
+	column int
+
+	// command is the command the option is in, whose settings are used to
+	// convert values
+	command *command
+}
+
+// makeOptionFrom makes a new option from the option node of a parsed definition
+func makeOptionFrom(node *syntax.Option) *option {
+
+	o := new(option)
+	o.rawArg = node.String()
+	o.column = node.Column
+
+	for _, name := range []string{node.Name, node.Alias} {
+		switch {
+		case strings.HasPrefix(name, "--"):
+			o.long = name[2:]
+		case strings.HasPrefix(name, "-"):
+			o.short = name[1:]
+		}
+	}
+
+	o.identifier = o.long
+	if o.identifier == "" {
+		o.identifier = o.short
+	}
+
+	o.captureType = node.Type
+	o.constraint = makeConstraint(node.Type, node.Constraint, constraintColumn(node.TypeColumn, node.Type))
+	o.repeatable = node.Repeatable
+	o.defaultValue = node.Default
+
+	return o
+
+}
+
+// isSwitch determines if this option takes no value
+func (o *option) isSwitch() bool {
+
+	return o.captureType == ""
+
+}
+
+// isRepeatable determines if this option may be given more than once
+func (o *option) isRepeatable() bool {
+
+	return o.repeatable
+
+}
+
+// names gets the names this option may be given by on the command line
+func (o *option) names() []string {
+
+	var names []string
+	if o.long != "" {
+		names = append(names, "--"+o.long)
+	}
+	if o.short != "" {
+		names = append(names, "-"+o.short)
+	}
+	return names
+
+}
+
+// represents determines if the cmdArg names this option. If the value is given
+// in the same cmdArg (as in "--timeout=30"), it is returned along with true for
+// hasValue.
+func (o *option) represents(cmdArg string) (represents bool, value string, hasValue bool) {
+
+	for _, name := range o.names() {
+		if cmdArg == name {
+			return true, "", false
+		}
+		if strings.HasPrefix(cmdArg, name+delimiterEquality) {
+			return true, cmdArg[len(name)+1:], true
+		}
+	}
+
+	return false, "", false
+
+}
+
+// value gets the value this option holds for the given occurrences on the
+// command line. Switches hold a bool (or a count if they are repeatable),
+// and options with a capture type hold the converted value (or a slice of
+// them if they are repeatable, or the default if they were not given).
+func (o *option) value(occurrences []string) interface{} {
+
+	switch {
+	case o.isSwitch() && o.isRepeatable():
+		return int64(len(occurrences))
+	case o.isSwitch():
+		return len(occurrences) > 0
+	case len(occurrences) == 0 && o.defaultValue != "":
+		value, _ := o.convert(o.defaultValue)
+		return value
+	case len(occurrences) == 0:
+		return nil
+	case o.isRepeatable():
+		return castValues(occurrences, o.convert)
+	}
+
+	value, _ := o.convert(occurrences[0])
+	return value
+
+}
+
+// expected describes what this option expects, for use in help and mismatch
+// errors
+func (o *option) expected() string {
+
+	if o.isSwitch() {
+		return describeType("bool")
+	}
+	return describeCapture(o.captureType, o.constraint)
+
+}
+
+// fallbackOccurrences gets the occurrences of this option represented by a
+// value taken from the environment or a config file. A switch is given if the
+// value is true, and another option is given the value.
+func (o *option) fallbackOccurrences(value string) ([]string, error) {
+
+	if !o.isSwitch() {
+		if _, err := o.convert(value); err != nil {
+			return nil, err
+		}
+		return []string{value}, nil
+	}
+
+	given, err := parseBool(value)
+	switch {
+	case err != nil:
+		return nil, err
+	case given.(bool):
+		return []string{""}, nil
+	}
+	return nil, nil
+
+}
+
+// convert converts the cmdArg into a value of the capture type of this option
+func (o *option) convert(cmdArg string) (interface{}, error) {
+
+	return o.command.convert(cmdArg, o.captureType, o.constraint)
+
+}
@@ -0,0 +1,100 @@
+package commander
+
+import (
+	"github.com/stretchr/commander/syntax"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const (
+	optSwitch           = "--force"
+	optShortSwitch      = "-v"
+	optSwitchAlias      = "--verbose|-v"
+	optRepeatableSwitch = "-v..."
+	optCaptureType      = "--timeout=(int)"
+	optRepeatable       = "[--tag|-t=(string)...]"
+)
+
+// makeOption makes a new option from the rawArg, which defines a single option
+func makeOption(rawArg string) *option {
+
+	o := new(option)
+	node, _ := singleNode(rawArg)
+	if optionNode, ok := node.(*syntax.Option); ok {
+		o = makeOptionFrom(optionNode)
+	}
+	o.rawArg = rawArg
+
+	return o
+
+}
+
+func TestOption_MakeOption(t *testing.T) {
+
+	o := makeOption(optSwitch)
+	assert.Equal(t, o.rawArg, optSwitch)
+	assert.Equal(t, o.long, "force")
+	assert.Equal(t, o.short, "")
+	assert.Equal(t, o.identifier, "force")
+	assert.True(t, o.isSwitch())
+	assert.False(t, o.isRepeatable())
+
+	o = makeOption(optShortSwitch)
+	assert.Equal(t, o.short, "v")
+	assert.Equal(t, o.identifier, "v")
+
+	o = makeOption(optSwitchAlias)
+	assert.Equal(t, o.long, "verbose")
+	assert.Equal(t, o.short, "v")
+	assert.Equal(t, o.identifier, "verbose")
+
+	o = makeOption(optRepeatableSwitch)
+	assert.True(t, o.isSwitch())
+	assert.True(t, o.isRepeatable())
+
+	o = makeOption(optCaptureType)
+	assert.Equal(t, o.identifier, "timeout")
+	assert.Equal(t, o.captureType, "int")
+	assert.False(t, o.isSwitch())
+
+	o = makeOption(optRepeatable)
+	assert.Equal(t, o.identifier, "tag")
+	assert.Equal(t, o.short, "t")
+	assert.Equal(t, o.captureType, "string")
+	assert.True(t, o.isRepeatable())
+
+}
+
+func TestOption_Represents(t *testing.T) {
+
+	o := makeOption(optSwitchAlias)
+
+	represents, _, hasValue := o.represents("--verbose")
+	assert.True(t, represents)
+	assert.False(t, hasValue)
+
+	represents, _, _ = o.represents("-v")
+	assert.True(t, represents)
+
+	represents, _, _ = o.represents("--verbosity")
+	assert.False(t, represents)
+
+	o = makeOption(optCaptureType)
+
+	represents, value, hasValue := o.represents("--timeout=30")
+	assert.True(t, represents)
+	assert.True(t, hasValue)
+	assert.Equal(t, value, "30")
+
+}
+
+func TestOption_Value(t *testing.T) {
+
+	assert.Equal(t, makeOption(optSwitch).value(nil), false)
+	assert.Equal(t, makeOption(optSwitch).value([]string{""}), true)
+	assert.Equal(t, makeOption(optRepeatableSwitch).value([]string{"", "", ""}), int64(3))
+	assert.Nil(t, makeOption(optCaptureType).value(nil))
+	assert.Equal(t, makeOption(optCaptureType).value([]string{"30"}), int64(30))
+	assert.Equal(t, makeOption(optRepeatable).value([]string{"a", "b"}), []string{"a", "b"})
+
+}
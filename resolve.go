@@ -0,0 +1,186 @@
+package commander
+
+// Source describes where the value of an argument or option came from
+type Source int
+
+const (
+	// SourceCommandLine means the value was given on the command line
+	SourceCommandLine Source = iota + 1
+
+	// SourceEnv means the value was taken from an environment variable
+	SourceEnv
+
+	// SourceConfig means the value was taken from a config file
+	SourceConfig
+
+	// SourceDefault means the value is the default
+	SourceDefault
+)
+
+// String gets a description of the source, such as "command line"
+func (s Source) String() string {
+
+	switch s {
+	case SourceCommandLine:
+		return "command line"
+	case SourceEnv:
+		return "environment"
+	case SourceConfig:
+		return "config file"
+	case SourceDefault:
+		return "default"
+	}
+	return "unknown"
+
+}
+
+// Value is the value of an argument or option of a command, as it would be
+// passed to the handler, and where it came from
+type Value struct {
+	// Identifier is the identifier of the argument or option
+	Identifier string
+
+	// Value is the value, converted to the capture type
+	Value interface{}
+
+	// Source is where the value came from
+	Source Source
+
+	// Origin is the name of the environment variable or the path of the config
+	// file and the line the value came from, if it came from either
+	Origin string
+}
+
+// Resolve gets the values that would be passed to the handler of the command
+// the arguments represent, and where each came from, without running the
+// handler. It is meant for debugging where values come from when they are
+// taken from the environment or config files.
+//
+// Resolve returns a *MismatchError if the arguments do not match any of the
+// mapped commands, or a value taken from the environment or a config file is
+// not valid.
+func (c *Commander) Resolve(args []string) ([]Value, error) {
+
+	if err := c.loadConfig(); err != nil {
+		return nil, err
+	}
+
+	if len(args) == 0 {
+		for _, cmd := range c.commands {
+			if cmd.isDefaultCommand() {
+				return nil, nil
+			}
+		}
+	} else if cmd := c.commandFor(args); cmd != nil {
+		if mismatch := c.ambiguousPrefix(cmd, args); mismatch != nil {
+			return nil, mismatch
+		}
+		values, mismatch := resolveValues(cmd, args)
+		if mismatch != nil {
+			mismatch.Definition = cmd.definition
+			mismatch.command = cmd
+			return nil, mismatch
+		}
+		return values, nil
+	}
+
+	return nil, c.closestMismatch(args)
+
+}
+
+// commandMap builds a map of indentifier,value to be passed to the handler.
+// Captured values are converted to their capture type, and variable
+// arguments are collected into a slice of that type. Options are added
+// under their identifiers. Optional arguments and options that were not
+// given are taken from the environment if they are bound to it, or from
+// the config files, or added with their defaults, if they have them.
+func commandMap(cmd *command, args []string) map[string]interface{} {
+	argMap, _ := resolveArgs(cmd, args)
+	return argMap
+}
+
+// resolveArgs builds the map of indentifier,value to be passed to the handler,
+// the same as commandMap, returning a MismatchError if a value taken from the
+// environment or a config file is not valid.
+func resolveArgs(cmd *command, args []string) (map[string]interface{}, *MismatchError) {
+	values, mismatch := resolveValues(cmd, args)
+	if mismatch != nil {
+		return nil, mismatch
+	}
+	argMap := make(map[string]interface{})
+	for _, value := range values {
+		argMap[value.Identifier] = value.Value
+	}
+	return argMap, nil
+}
+
+// resolveValues gets the values of the arguments and options of the command
+// represented by args, and where each came from
+func resolveValues(cmd *command, args []string) ([]Value, *MismatchError) {
+
+	var values []Value
+	parsed, _ := cmd.parseOptions(args)
+
+	// collect the positional arguments matched to each of the arguments
+	given := make([][]string, len(cmd.arguments))
+	matched, _ := cmd.match(parsed.positional)
+	for i, argIndex := range matched {
+		given[argIndex] = append(given[argIndex], parsed.positional[i])
+	}
+
+	for i, a := range cmd.arguments {
+		switch {
+		case len(given[i]) > 0 && a.isLiteral():
+		case len(given[i]) > 0 && a.isVariable():
+			values = append(values, Value{a.identifier, a.values(given[i]), SourceCommandLine, ""})
+		case len(given[i]) > 0:
+			values = append(values, Value{a.identifier, a.value(given[i][0]), SourceCommandLine, ""})
+		case a.isCapture() && a.isOptional() && !a.isVariable():
+			value, source, origin, ok := cmd.fallback(a.identifier)
+			if ok {
+				if err := a.conversionError(value); err != nil {
+					return nil, fallbackMismatch(source, origin, value, a.identifier, a.expected(), err)
+				}
+				values = append(values, Value{a.identifier, a.value(value), source, origin})
+			} else if a.hasDefault() {
+				values = append(values, Value{a.identifier, a.value(a.defaultValue), SourceDefault, ""})
+			}
+		}
+	}
+
+	for _, o := range cmd.options {
+		occurrences := parsed.occurrences[o.identifier]
+		source, origin := SourceCommandLine, ""
+		if len(occurrences) == 0 {
+			source = SourceDefault
+			if value, fallbackSource, fallbackOrigin, ok := cmd.fallback(o.identifier); ok && !o.isRepeatable() {
+				fallbackOccurrences, err := o.fallbackOccurrences(value)
+				if err != nil {
+					return nil, fallbackMismatch(fallbackSource, fallbackOrigin, value, o.identifier, o.expected(), err)
+				}
+				occurrences, source, origin = fallbackOccurrences, fallbackSource, fallbackOrigin
+			}
+		}
+		if value := o.value(occurrences); value != nil {
+			values = append(values, Value{o.identifier, value, source, origin})
+		}
+	}
+
+	return values, nil
+
+}
+
+// fallback gets the value for the identifier from the environment, if it is
+// bound to an environment variable that is set, or else from the config
+// files, returning where it came from and false if it is in neither
+func (c *command) fallback(identifier string) (string, Source, string, bool) {
+
+	if value, name, ok := c.lookupEnv(identifier); ok {
+		return value, SourceEnv, name, true
+	}
+	if value, origin, ok := c.lookupConfig(identifier); ok {
+		return value, SourceConfig, origin, true
+	}
+	return "", 0, "", false
+
+}
@@ -0,0 +1,150 @@
+package syntax
+
+// Node is a literal, list, capture, option or optional group in a definition
+type Node interface {
+	// Pos gets the column of the definition the node starts at, starting
+	// from 1
+	Pos() int
+
+	// String gets the node as it would be written in a definition
+	String() string
+}
+
+// Definition is a parsed definition, made up of the nodes separated by spaces
+type Definition struct {
+	// Nodes contains the nodes of the definition, in order
+	Nodes []Node
+}
+
+// Literal is an argument that must be given as it is written, such as
+// "create"
+type Literal struct {
+	// Column is the column the literal starts at
+	Column int
+
+	// Value is the text of the literal, with any quotes and escapes removed
+	Value string
+}
+
+// List is an argument that must be one of the items, such as
+// "kind=project|account"
+type List struct {
+	// Column is the column the list starts at
+	Column int
+
+	// Identifier is the identifier of the list
+	Identifier string
+
+	// Items contains the items of the list, in order
+	Items []string
+}
+
+// Capture is an argument whose value is converted to a type, such as
+// "name=(string)" or "[retries=(int:0..10)=3]"
+type Capture struct {
+	// Column is the column the capture starts at
+	Column int
+
+	// Identifier is the identifier of the capture
+	Identifier string
+
+	// Type is the name of the capture type, such as "int"
+	Type string
+
+	// TypeColumn is the column the name of the capture type starts at
+	TypeColumn int
+
+	// Constraint is the constraint written after the capture type, such as
+	// "0..10", or an empty string if there is none
+	Constraint string
+
+	// Variable is whether the capture takes all of the rest of the arguments
+	Variable bool
+
+	// Default is the default value of the capture, or an empty string if
+	// there is none
+	Default string
+}
+
+// Option is a named flag, such as "--force", "--verbose|-v" or
+// "--timeout=(duration)=30s"
+type Option struct {
+	// Column is the column the option starts at
+	Column int
+
+	// Name is the name of the option, including its dashes, such as "--verbose"
+	Name string
+
+	// Alias is the other name of the option, such as "-v", or an empty string
+	// if there is none
+	Alias string
+
+	// Type is the name of the capture type of the value of the option, or an
+	// empty string if the option is a switch
+	Type string
+
+	// TypeColumn is the column the name of the capture type starts at
+	TypeColumn int
+
+	// Constraint is the constraint written after the capture type, or an empty
+	// string if there is none
+	Constraint string
+
+	// Repeatable is whether the option may be given more than once
+	Repeatable bool
+
+	// Default is the default value of the option, or an empty string if there
+	// is none
+	Default string
+}
+
+// Optional is a group of nodes in [ ] square brackets, which may be left out
+// together
+type Optional struct {
+	// Column is the column of the opening [
+	Column int
+
+	// Nodes contains the nodes in the group, in order
+	Nodes []Node
+}
+
+// Pos gets the column the literal starts at
+func (l *Literal) Pos() int {
+	return l.Column
+}
+
+// Pos gets the column the list starts at
+func (l *List) Pos() int {
+	return l.Column
+}
+
+// Pos gets the column the capture starts at
+func (c *Capture) Pos() int {
+	return c.Column
+}
+
+// Pos gets the column the option starts at
+func (o *Option) Pos() int {
+	return o.Column
+}
+
+// Pos gets the column of the opening [ of the group
+func (o *Optional) Pos() int {
+	return o.Column
+}
+
+// Inspect calls f for each of the nodes in order, including those in
+// optional groups, which are visited after the group itself. If f returns
+// false, the nodes in that group are not visited.
+func Inspect(nodes []Node, f func(Node) bool) {
+
+	for _, node := range nodes {
+		if !f(node) {
+			continue
+		}
+		if optional, ok := node.(*Optional); ok {
+			Inspect(optional.Nodes, f)
+		}
+	}
+
+}
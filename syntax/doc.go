@@ -0,0 +1,44 @@
+// Package syntax parses the definitions of commands, such as
+// "create kind=project|account name=(string) [--force]", into an abstract
+// syntax tree, and prints them back out.
+//
+// It is used by commander to map commands, and can be used by tools such as
+// documentation generators, linters and completion scripts to read
+// definitions without re-implementing the grammar.
+//
+// # Grammar
+//
+// The grammar of a definition, in EBNF, is:
+//
+//	definition  = [ node { " " node } ] .
+//	node        = optional | option | literal | list | capture .
+//	optional    = "[" node { " " node } "]" .
+//	literal     = word .
+//	list        = word "=" word "|" word { "|" word } .
+//	capture     = word "=" captureType [ "..." ] [ "=" word ] .
+//	option      = name [ "|" name ] [ "=" captureType [ "=" word ] ] [ "..." ] .
+//	captureType = "(" typeName [ ":" constraint ] ")" .
+//	name        = ( "-" | "--" ) alphanumeric { alphanumeric | "_" | "-" } .
+//	word        = unquoted | quoted .
+//	unquoted    = ( plain | escape ) { plain | escape } .
+//	quoted      = `"` { plain | reserved | escape } `"` .
+//	escape      = `\` character .
+//	reserved    = " " | "[" | "]" | "(" | ")" | "=" | "|" | `"` .
+//	plain       = any character except reserved characters and `\` .
+//	typeName    = plain { plain } .
+//	constraint  = any characters in which ( and ) are balanced .
+//
+// Nodes are separated by exactly one space. A literal may not be empty, and
+// may not start with "-" unless it is quoted, so that it is not mistaken for
+// an option. The default of an option that ends with "..." must be quoted, so
+// that it is not mistaken for a repeatable option. Within a constraint, a \
+// escapes the character after it, which is kept, so that patterns may
+// contain unbalanced parentheses.
+//
+// For example:
+//
+//	copy [mode=fast|slow] "source file"=(string) [dst=(string) [--force]]
+//	grep pattern=(string:/^[a-z]+$/) files=(string)...
+//	greet [name=(string)="the world"]
+//	say "-n" "a \"quoted\" word"
+package syntax
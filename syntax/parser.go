@@ -0,0 +1,473 @@
+package syntax
+
+import (
+	"fmt"
+	"strings"
+)
+
+// reserved contains the characters that must be quoted or escaped to be used
+// in a word
+const reserved = " []()=|\"\\"
+
+// Error describes why a definition could not be parsed, and where
+type Error struct {
+	// Column is the column of the definition the problem is at, starting
+	// from 1
+	Column int
+
+	// Message describes the problem
+	Message string
+}
+
+// Error gets the column and a description of the problem, for example
+// "column 9: the capture type is not closed with )"
+func (e *Error) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+// Parse parses the definition, returning an *Error describing the first
+// problem if it does not follow the grammar. An empty definition has no nodes.
+func Parse(definition string) (*Definition, error) {
+
+	p := &parser{definition: definition}
+	nodes, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if !p.atEnd() {
+		return nil, p.errorf(p.pos, "unexpected ]")
+	}
+	return &Definition{Nodes: nodes}, nil
+
+}
+
+// parser holds the state of the parsing of a definition
+type parser struct {
+	// definition is the definition being parsed
+	definition string
+
+	// pos is the offset in the definition of the next character
+	pos int
+}
+
+// errorf makes an *Error for the problem at offset pos of the definition
+func (p *parser) errorf(pos int, format string, a ...interface{}) error {
+	return &Error{Column: pos + 1, Message: fmt.Sprintf(format, a...)}
+}
+
+// atEnd determines if the whole definition has been parsed
+func (p *parser) atEnd() bool {
+	return p.pos >= len(p.definition)
+}
+
+// peek gets the next character, or 0 at the end of the definition
+func (p *parser) peek() byte {
+
+	if p.atEnd() {
+		return 0
+	}
+	return p.definition[p.pos]
+
+}
+
+// atBoundary determines if the next character ends a node
+func (p *parser) atBoundary() bool {
+	return p.atEnd() || p.peek() == ' ' || p.peek() == ']'
+}
+
+// unexpected makes an *Error for the next character, which is not expected
+// where it is, adding the context to the message if it is not empty
+func (p *parser) unexpected(context string) error {
+
+	c := string(p.peek())
+	if c == " " {
+		c = "space"
+	}
+	if context == "" {
+		return p.errorf(p.pos, "unexpected %s", c)
+	}
+	return p.errorf(p.pos, "unexpected %s %s", c, context)
+
+}
+
+// parseNodes parses the nodes separated by spaces up to the end of the
+// definition or a closing ]
+func (p *parser) parseNodes() ([]Node, error) {
+
+	var nodes []Node
+	if p.atEnd() {
+		return nodes, nil
+	}
+
+	for {
+		node, err := p.parseNode()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		if p.peek() != ' ' {
+			return nodes, nil
+		}
+		p.pos++
+		if p.atBoundary() {
+			return nil, p.errorf(p.pos, "expected an argument, but found a space")
+		}
+	}
+
+}
+
+// parseNode parses an optional group, option, literal, list or capture
+func (p *parser) parseNode() (Node, error) {
+
+	switch p.peek() {
+	case ' ':
+		return nil, p.errorf(p.pos, "expected an argument, but found a space")
+	case ']':
+		return nil, p.errorf(p.pos, "unexpected ]")
+	case '[':
+		return p.parseOptional()
+	case '-':
+		return p.parseOption()
+	}
+	return p.parseArgument()
+
+}
+
+// parseOptional parses the nodes in [ ] square brackets
+func (p *parser) parseOptional() (Node, error) {
+
+	start := p.pos
+	p.pos++
+	if p.peek() == ']' {
+		return nil, p.errorf(start, "expected an argument between [ and ]")
+	}
+
+	nodes, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ']' {
+		return nil, p.errorf(start, "the optional argument is not closed with ]")
+	}
+	p.pos++
+
+	if !p.atBoundary() {
+		return nil, p.unexpected("after ]")
+	}
+	return &Optional{Column: start + 1, Nodes: nodes}, nil
+
+}
+
+// parseArgument parses a literal, list or capture
+func (p *parser) parseArgument() (Node, error) {
+
+	start := p.pos
+	word, quoted, err := p.parseWord()
+	if err != nil {
+		return nil, err
+	}
+
+	if word == "" && !quoted {
+		switch p.peek() {
+		case '=':
+			return nil, p.errorf(p.pos, "expected an identifier before =")
+		case '|':
+			return nil, p.errorf(p.pos, "unexpected |, a list needs an identifier, as in kind=a|b")
+		}
+		return nil, p.unexpected("")
+	}
+
+	switch p.peek() {
+	case '=':
+		p.pos++
+		if p.peek() == '(' {
+			return p.parseCapture(start, word)
+		}
+		return p.parseList(start, word)
+	case '|':
+		return nil, p.errorf(p.pos, "unexpected |, a list needs an identifier, as in kind=a|b")
+	}
+
+	if !p.atBoundary() {
+		return nil, p.unexpected("")
+	}
+	if word == "" {
+		return nil, p.errorf(start, "a literal may not be empty")
+	}
+	return &Literal{Column: start + 1, Value: word}, nil
+
+}
+
+// parseList parses the items of a list, after the = following its identifier
+func (p *parser) parseList(start int, identifier string) (Node, error) {
+
+	list := &List{Column: start + 1, Identifier: identifier}
+	first := p.pos
+
+	for {
+		item, quoted, err := p.parseWord()
+		if err != nil {
+			return nil, err
+		}
+		if item == "" && !quoted {
+			if p.atBoundary() || p.peek() == '|' {
+				return nil, p.errorf(p.pos, "expected a list item")
+			}
+			return nil, p.unexpected("in the list")
+		}
+		list.Items = append(list.Items, item)
+
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+	}
+
+	if !p.atBoundary() {
+		return nil, p.unexpected("in the list")
+	}
+	if len(list.Items) == 1 {
+		return nil, p.errorf(first, "a list needs more than one item, as in kind=a|b")
+	}
+	return list, nil
+
+}
+
+// parseCapture parses the capture type of a capture and what follows it,
+// after the = following its identifier
+func (p *parser) parseCapture(start int, identifier string) (Node, error) {
+
+	capture := &Capture{Column: start + 1, Identifier: identifier}
+
+	var err error
+	if capture.Type, capture.TypeColumn, capture.Constraint, err = p.parseCaptureType(); err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(p.definition[p.pos:], "...") {
+		capture.Variable = true
+		p.pos += len("...")
+	}
+
+	if p.peek() == '=' {
+		p.pos++
+		if capture.Default, err = p.parseDefault(); err != nil {
+			return nil, err
+		}
+		if !p.atBoundary() {
+			return nil, p.unexpected("after the default")
+		}
+	}
+
+	if !p.atBoundary() {
+		return nil, p.unexpected("after the capture type")
+	}
+	return capture, nil
+
+}
+
+// parseCaptureType parses a capture type in ( ) parentheses, returning the
+// name of the type, the column it starts at and the constraint
+func (p *parser) parseCaptureType() (string, int, string, error) {
+
+	open := p.pos
+	p.pos++
+	typeStart := p.pos
+	for !p.atEnd() && p.peek() != ':' && strings.IndexByte(reserved, p.peek()) < 0 {
+		p.pos++
+	}
+	captureType := p.definition[typeStart:p.pos]
+
+	switch {
+	case p.atEnd():
+		return "", 0, "", p.errorf(open, "the capture type is not closed with )")
+	case p.peek() != ')' && p.peek() != ':':
+		return "", 0, "", p.unexpected("in the capture type")
+	case captureType == "":
+		return "", 0, "", p.errorf(p.pos, "expected a capture type")
+	}
+
+	var constraint string
+	if p.peek() == ':' {
+		p.pos++
+		constraintStart := p.pos
+		for depth := 0; ; p.pos++ {
+			if p.atEnd() {
+				return "", 0, "", p.errorf(open, "the capture type is not closed with )")
+			}
+			switch p.peek() {
+			case '\\':
+				p.pos++
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth < 0 {
+				break
+			}
+		}
+		constraint = p.definition[constraintStart:p.pos]
+		if constraint == "" {
+			return "", 0, "", p.errorf(p.pos, "expected a constraint after :")
+		}
+	}
+
+	p.pos++
+	return captureType, typeStart + 1, constraint, nil
+
+}
+
+// parseOption parses an option, along with its alias, capture type and
+// default
+func (p *parser) parseOption() (Node, error) {
+
+	option := &Option{Column: p.pos + 1}
+
+	var ok bool
+	if option.Name, ok = p.parseName(); !ok {
+		return nil, p.errorf(option.Column-1, "expected an option such as --name, -n or --name=(type)")
+	}
+
+	if p.peek() == '|' {
+		p.pos++
+		aliasStart := p.pos
+		if option.Alias, ok = p.parseName(); !ok {
+			return nil, p.errorf(aliasStart, "expected an option such as --name, -n or --name=(type)")
+		}
+	}
+
+	if p.peek() == '=' {
+		p.pos++
+		if p.peek() != '(' {
+			return nil, p.errorf(p.pos, "expected a capture type in ( ), as in --name=(type)")
+		}
+		var err error
+		if option.Type, option.TypeColumn, option.Constraint, err = p.parseCaptureType(); err != nil {
+			return nil, err
+		}
+		if p.peek() == '=' {
+			p.pos++
+			quoted := p.peek() == '"'
+			if option.Default, err = p.parseDefault(); err != nil {
+				return nil, err
+			}
+			// an unquoted default ending in ... is followed by the ... of
+			// a repeatable option
+			if !quoted && len(option.Default) > len("...") && strings.HasSuffix(option.Default, "...") {
+				option.Default = strings.TrimSuffix(option.Default, "...")
+				option.Repeatable = true
+			}
+		}
+	}
+
+	if strings.HasPrefix(p.definition[p.pos:], "...") {
+		option.Repeatable = true
+		p.pos += len("...")
+	}
+
+	if !p.atBoundary() {
+		return nil, p.unexpected("after the option")
+	}
+	return option, nil
+
+}
+
+// parseName parses the name of an option, such as "--name" or "-n", returning
+// false if there is not one next
+func (p *parser) parseName() (string, bool) {
+
+	start := p.pos
+	end := start
+	for end < len(p.definition) && end-start < 2 && p.definition[end] == '-' {
+		end++
+	}
+	if end == start || end == len(p.definition) || !isAlphanumeric(p.definition[end]) {
+		return "", false
+	}
+	for end < len(p.definition) && (isAlphanumeric(p.definition[end]) || p.definition[end] == '_' || p.definition[end] == '-') {
+		end++
+	}
+	p.pos = end
+	return p.definition[start:end], true
+
+}
+
+// isAlphanumeric determines if c is an ASCII letter or digit
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// parseDefault parses the default value after the =
+func (p *parser) parseDefault() (string, error) {
+
+	value, quoted, err := p.parseWord()
+	if err != nil {
+		return "", err
+	}
+	if value == "" && !quoted {
+		return "", p.errorf(p.pos, "expected a default value after =")
+	}
+	return value, nil
+
+}
+
+// parseWord parses a quoted word, or the characters up to the next reserved
+// character, returning the word with any quotes and escapes removed and
+// whether it was quoted
+func (p *parser) parseWord() (string, bool, error) {
+
+	if p.peek() == '"' {
+		return p.parseQuoted()
+	}
+
+	var word strings.Builder
+	for !p.atEnd() {
+		c := p.peek()
+		if c == '\\' {
+			if p.pos+1 == len(p.definition) {
+				return "", false, p.errorf(p.pos, "expected a character after \\")
+			}
+			word.WriteByte(p.definition[p.pos+1])
+			p.pos += 2
+			continue
+		}
+		if strings.IndexByte(reserved, c) >= 0 {
+			break
+		}
+		word.WriteByte(c)
+		p.pos++
+	}
+	return word.String(), false, nil
+
+}
+
+// parseQuoted parses a word in " " double quotes
+func (p *parser) parseQuoted() (string, bool, error) {
+
+	start := p.pos
+	p.pos++
+
+	var word strings.Builder
+	for {
+		if p.atEnd() {
+			return "", false, p.errorf(start, "the quoted word is not closed with \"")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return word.String(), true, nil
+		case '\\':
+			if p.pos+1 == len(p.definition) {
+				return "", false, p.errorf(p.pos, "expected a character after \\")
+			}
+			p.pos++
+			c = p.peek()
+		}
+		word.WriteByte(c)
+		p.pos++
+	}
+
+}
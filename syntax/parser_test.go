@@ -0,0 +1,118 @@
+package syntax
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParser_Parse(t *testing.T) {
+
+	definition, err := Parse(`copy [mode=fast|slow] "source file"=(string:/^[a-z]+$/) [dst=(string) [--force|-f]] --tag=(string)=a...`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, definition.Nodes, []Node{
+			&Literal{Column: 1, Value: "copy"},
+			&Optional{Column: 6, Nodes: []Node{
+				&List{Column: 7, Identifier: "mode", Items: []string{"fast", "slow"}},
+			}},
+			&Capture{Column: 23, Identifier: "source file", Type: "string", TypeColumn: 38, Constraint: "/^[a-z]+$/"},
+			&Optional{Column: 57, Nodes: []Node{
+				&Capture{Column: 58, Identifier: "dst", Type: "string", TypeColumn: 63},
+				&Optional{Column: 71, Nodes: []Node{
+					&Option{Column: 72, Name: "--force", Alias: "-f"},
+				}},
+			}},
+			&Option{Column: 85, Name: "--tag", Type: "string", TypeColumn: 92, Default: "a", Repeatable: true},
+		})
+	}
+
+	definition, err = Parse(`greet [names=(string)...="the \"world\""] "-n" a\|b`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, definition.Nodes, []Node{
+			&Literal{Column: 1, Value: "greet"},
+			&Optional{Column: 7, Nodes: []Node{
+				&Capture{Column: 8, Identifier: "names", Type: "string", TypeColumn: 15, Variable: true, Default: `the "world"`},
+			}},
+			&Literal{Column: 43, Value: "-n"},
+			&Literal{Column: 48, Value: "a|b"},
+		})
+	}
+
+	definition, err = Parse(`match value=(string:/(a|b)\)/)`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, definition.Nodes[1].(*Capture).Constraint, `/(a|b)\)/`)
+	}
+
+	definition, err = Parse("")
+	if assert.NoError(t, err) {
+		assert.Empty(t, definition.Nodes)
+	}
+
+}
+
+func TestParser_Errors(t *testing.T) {
+
+	tests := []struct {
+		definition string
+		column     int
+		message    string
+	}{
+		{" add", 1, "expected an argument, but found a space"},
+		{"add ", 5, "expected an argument, but found a space"},
+		{"add  num=(int)", 5, "expected an argument, but found a space"},
+		{"add num=(int", 9, "the capture type is not closed with )"},
+		{"add num=(int:/(/)", 9, "the capture type is not closed with )"},
+		{"add num=()", 10, "expected a capture type"},
+		{"add num=(int:)", 14, "expected a constraint after :"},
+		{"add num=(int)x", 14, "unexpected x after the capture type"},
+		{"add num=(in|t)", 12, "unexpected | in the capture type"},
+		{"add [num=(int)", 5, "the optional argument is not closed with ]"},
+		{"add [[a] b", 5, "the optional argument is not closed with ]"},
+		{"add num=(int)]", 14, "unexpected ]"},
+		{"add [a]b", 8, "unexpected b after ]"},
+		{"add []", 5, "expected an argument between [ and ]"},
+		{"add =(int)", 5, "expected an identifier before ="},
+		{"add a|b", 6, "unexpected |, a list needs an identifier, as in kind=a|b"},
+		{"add a(b)", 6, "unexpected ("},
+		{"add kind=a", 10, "a list needs more than one item, as in kind=a|b"},
+		{"add kind=a||b", 12, "expected a list item"},
+		{"add kind=a|(b)", 12, "unexpected ( in the list"},
+		{"add [n=(int)=]", 14, "expected a default value after ="},
+		{`add "a b`, 5, `the quoted word is not closed with "`},
+		{`add a\`, 6, `expected a character after \`},
+		{"add -=x", 5, "expected an option such as --name, -n or --name=(type)"},
+		{"add ---force", 5, "expected an option such as --name, -n or --name=(type)"},
+		{"add --force|x", 13, "expected an option such as --name, -n or --name=(type)"},
+		{"add --timeout=int", 15, "expected a capture type in ( ), as in --name=(type)"},
+		{"add --force(", 12, "unexpected ( after the option"},
+		{`say ""`, 5, "a literal may not be empty"},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test.definition)
+		assert.Equal(t, err, &Error{test.column, test.message}, test.definition)
+	}
+
+	_, err := Parse("add num=(int")
+	assert.Equal(t, err.Error(), "column 9: the capture type is not closed with )")
+
+}
+
+func TestParser_Inspect(t *testing.T) {
+
+	definition, _ := Parse("copy [src=(string) [--force]] dst=(string)")
+
+	var visited []string
+	Inspect(definition.Nodes, func(node Node) bool {
+		visited = append(visited, node.String())
+		return true
+	})
+	assert.Equal(t, visited, []string{"copy", "[src=(string) [--force]]", "src=(string)", "[--force]", "--force", "dst=(string)"})
+
+	visited = nil
+	Inspect(definition.Nodes, func(node Node) bool {
+		visited = append(visited, node.String())
+		return false
+	})
+	assert.Equal(t, visited, []string{"copy", "[src=(string) [--force]]", "dst=(string)"})
+
+}
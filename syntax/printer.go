@@ -0,0 +1,119 @@
+package syntax
+
+import (
+	"strings"
+)
+
+// Print gets the nodes as they would be written in a definition, separated by
+// spaces. Parsing what it returns gives the same nodes, apart from their
+// columns.
+func Print(nodes ...Node) string {
+
+	written := make([]string, len(nodes))
+	for i, node := range nodes {
+		written[i] = node.String()
+	}
+	return strings.Join(written, " ")
+
+}
+
+// Quote gets s as a word of a definition, in " " double quotes if it is empty
+// or contains reserved characters such as spaces or |
+func Quote(s string) string {
+
+	if s != "" && !strings.ContainsAny(s, reserved) {
+		return s
+	}
+	return quote(s)
+
+}
+
+// quoteFirst gets s as the first word of a node, which is also in " " double
+// quotes if it starts with "-", so that it is not mistaken for an option
+func quoteFirst(s string) string {
+
+	if strings.HasPrefix(s, "-") {
+		return quote(s)
+	}
+	return Quote(s)
+
+}
+
+// quote gets s in " " double quotes, escaping any quotes and backslashes in it
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// String gets the definition as it would be written
+func (d *Definition) String() string {
+	return Print(d.Nodes...)
+}
+
+// String gets the literal as it would be written in a definition
+func (l *Literal) String() string {
+	return quoteFirst(l.Value)
+}
+
+// String gets the list as it would be written in a definition
+func (l *List) String() string {
+
+	items := make([]string, len(l.Items))
+	for i, item := range l.Items {
+		items[i] = Quote(item)
+	}
+	return quoteFirst(l.Identifier) + "=" + strings.Join(items, "|")
+
+}
+
+// String gets the capture as it would be written in a definition
+func (c *Capture) String() string {
+
+	written := quoteFirst(c.Identifier) + "=" + printCaptureType(c.Type, c.Constraint)
+	if c.Variable {
+		written += "..."
+	}
+	if c.Default != "" {
+		written += "=" + Quote(c.Default)
+	}
+	return written
+
+}
+
+// String gets the option as it would be written in a definition
+func (o *Option) String() string {
+
+	written := o.Name
+	if o.Alias != "" {
+		written += "|" + o.Alias
+	}
+	if o.Type != "" {
+		written += "=" + printCaptureType(o.Type, o.Constraint)
+	}
+	if o.Default != "" {
+		if strings.HasSuffix(o.Default, "...") {
+			written += "=" + quote(o.Default)
+		} else {
+			written += "=" + Quote(o.Default)
+		}
+	}
+	if o.Repeatable {
+		written += "..."
+	}
+	return written
+
+}
+
+// String gets the optional group as it would be written in a definition
+func (o *Optional) String() string {
+	return "[" + Print(o.Nodes...) + "]"
+}
+
+// printCaptureType gets the capture type and constraint in ( ) parentheses
+func printCaptureType(captureType, constraint string) string {
+
+	if constraint == "" {
+		return "(" + captureType + ")"
+	}
+	return "(" + captureType + ":" + constraint + ")"
+
+}
@@ -0,0 +1,50 @@
+package syntax
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrinter_RoundTrip(t *testing.T) {
+
+	for _, definition := range []string{
+		"",
+		"create kind=project|account name=(string) [description=(string)]",
+		"copy [mode=fast|slow] src=(string) [all] dst=(string)",
+		"copy [src=(string) [--force|-f]] dst=(string)",
+		"retry [times=(int:1..10)=3] [names=(string)...=all]",
+		"serve [--port|-p=(int:1..65535)=8080] [--tag=(string)...] -v...",
+		`grep pattern=(string:/^[a-z]+( [a-z]+)*$/) files=(string)...`,
+		`greet "source file"=(string) kind="a b"|"c|d" "-n" [x=(string)="the \"world\""]`,
+		`tag --label=(string)="a..." "x\\y"`,
+		`set "-x"=(string) "-k"=a|b`,
+	} {
+		parsed, err := Parse(definition)
+		if assert.NoError(t, err, definition) {
+			assert.Equal(t, parsed.String(), definition)
+		}
+	}
+
+}
+
+func TestPrinter_Print(t *testing.T) {
+
+	assert.Equal(t, Print(
+		&Literal{Value: "say"},
+		&Literal{Value: "-n"},
+		&List{Identifier: "kind", Items: []string{"a b", "c"}},
+		&Optional{Nodes: []Node{&Capture{Identifier: "word", Type: "string", Default: `a\b`}}},
+		&Option{Name: "--label", Type: "string", Default: "wait..."},
+	), `say "-n" kind="a b"|c [word=(string)="a\\b"] --label=(string)="wait..."`)
+
+	assert.Equal(t, Quote("plain"), "plain")
+	assert.Equal(t, Quote(""), `""`)
+	assert.Equal(t, Quote(`say "hi"`), `"say \"hi\""`)
+
+	// printing what is parsed gives the same nodes
+	parsed, _ := Parse(`copy a\ b [x=y|"z w"]`)
+	reparsed, _ := Parse(parsed.String())
+	assert.Equal(t, reparsed.String(), `copy "a b" [x=y|"z w"]`)
+	assert.Equal(t, reparsed.Nodes[0], parsed.Nodes[0])
+
+}
@@ -0,0 +1,16 @@
+//go:build darwin || freebsd || netbsd || openbsd || dragonfly
+// +build darwin freebsd netbsd openbsd dragonfly
+
+package commander
+
+import (
+	"syscall"
+)
+
+const (
+	// ioctlGetTermios is the ioctl request that reads the terminal settings
+	ioctlGetTermios = syscall.TIOCGETA
+
+	// ioctlSetTermios is the ioctl request that changes the terminal settings
+	ioctlSetTermios = syscall.TIOCSETA
+)
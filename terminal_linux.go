@@ -0,0 +1,13 @@
+package commander
+
+import (
+	"syscall"
+)
+
+const (
+	// ioctlGetTermios is the ioctl request that reads the terminal settings
+	ioctlGetTermios = syscall.TCGETS
+
+	// ioctlSetTermios is the ioctl request that changes the terminal settings
+	ioctlSetTermios = syscall.TCSETS
+)
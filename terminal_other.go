@@ -0,0 +1,24 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package commander
+
+import (
+	"errors"
+)
+
+// isTerminal determines if the file descriptor is a terminal. Terminals are
+// not supported on this platform, so the console reads plain lines.
+func isTerminal(fd int) bool {
+
+	return false
+
+}
+
+// makeRaw puts the terminal into raw mode. Raw mode is not supported on this
+// platform.
+func makeRaw(fd int) (func(), error) {
+
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+
+}
@@ -0,0 +1,66 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+// +build linux darwin freebsd netbsd openbsd dragonfly
+
+package commander
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// getTermios reads the terminal settings of the file descriptor
+func getTermios(fd int) (*syscall.Termios, error) {
+
+	termios := new(syscall.Termios)
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return nil, errno
+	}
+	return termios, nil
+
+}
+
+// setTermios changes the terminal settings of the file descriptor
+func setTermios(fd int, termios *syscall.Termios) error {
+
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(termios))); errno != 0 {
+		return errno
+	}
+	return nil
+
+}
+
+// isTerminal determines if the file descriptor is a terminal
+func isTerminal(fd int) bool {
+
+	_, err := getTermios(fd)
+	return err == nil
+
+}
+
+// makeRaw puts the terminal into raw mode, so that each key press (including
+// Ctrl-C) can be read as it happens without being echoed. The returned func restores the terminal
+// to the way it was.
+func makeRaw(fd int) (func(), error) {
+
+	original, err := getTermios(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *original
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+
+	if err := setTermios(fd, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		setTermios(fd, original)
+	}, nil
+
+}
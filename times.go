@@ -0,0 +1,230 @@
+package commander
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeType is the name of the capture type for times, which is converted using
+// the time settings of the command rather than the registered parse func
+const timeType string = "time"
+
+// timeLayouts contains the layouts tried, in order, when converting an arg to
+// a time if no other layouts have been set
+var timeLayouts = []string{
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+	time.RFC822,
+	time.RFC822Z,
+	time.RFC850,
+	time.RFC1123,
+	time.RFC1123Z,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	time.Kitchen,
+	"15:04",
+}
+
+var (
+	// relativeTimeRegex represents the regexp for relative times, such as -2h
+	// or +1w3d. Amounts of days and weeks are whole numbers.
+	relativeTimeRegex = regexp.MustCompile(`^([+-])((?:\d+(?:\.\d+)?(?:ns|us|µs|ms|s|m|h)|\d+(?:d|w))+)$`)
+
+	// relativeTimePartRegex represents the regexp for each of the amounts in a
+	// relative time
+	relativeTimePartRegex = regexp.MustCompile(`(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h|d|w)`)
+)
+
+// timeSettings holds the settings used to convert arguments to times. Unset
+// settings are left nil, so that they can be inherited.
+type timeSettings struct {
+	// layouts contains the layouts tried, in order
+	layouts []string
+
+	// location is the location of times given without a time zone
+	location *time.Location
+
+	// relative is whether relative times, such as now or -2h, are allowed
+	relative *bool
+
+	// now gets the current time, which relative times are relative to
+	now func() time.Time
+}
+
+// defaultTimeSettings are the time settings used unless they are changed for
+// the Commander or the command
+var defaultTimeSettings = timeSettings{
+	layouts:  timeLayouts,
+	location: time.UTC,
+	relative: new(bool),
+	now:      time.Now,
+}
+
+// with gets these settings with any settings that are set in other replacing
+// them
+func (s timeSettings) with(other timeSettings) timeSettings {
+
+	if other.layouts != nil {
+		s.layouts = other.layouts
+	}
+	if other.location != nil {
+		s.location = other.location
+	}
+	if other.relative != nil {
+		s.relative = other.relative
+	}
+	if other.now != nil {
+		s.now = other.now
+	}
+	return s
+
+}
+
+// parse converts the arg to a time.Time, using the first of the layouts it is
+// in. Times without a date, such as 3:04PM, are on the current day.
+func (s timeSettings) parse(arg string) (interface{}, error) {
+
+	now := s.now().In(s.location)
+
+	if *s.relative {
+		if value, ok := parseRelativeTime(arg, now); ok {
+			return value, nil
+		}
+	}
+
+	for _, layout := range s.layouts {
+		value, err := time.ParseInLocation(layout, arg, s.location)
+		if err != nil {
+			continue
+		}
+		if value.Year() == 0 && value.YearDay() == 1 {
+			value = time.Date(now.Year(), now.Month(), now.Day(),
+				value.Hour(), value.Minute(), value.Second(), value.Nanosecond(), value.Location())
+		}
+		return value, nil
+	}
+
+	if *s.relative {
+		return nil, errors.New("not in a known time layout, or a relative time such as now, yesterday or -2h")
+	}
+	return nil, errors.New("not in a known time layout")
+
+}
+
+// parseRelativeTime converts the arg to a time relative to now, returning
+// false if it is not a relative time. Relative times are now, today,
+// yesterday and tomorrow (the last three at midnight), or a signed amount
+// such as -2h, +30m or -1w2d.
+func parseRelativeTime(arg string, now time.Time) (time.Time, bool) {
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch strings.ToLower(arg) {
+	case "now":
+		return now, true
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), true
+	}
+
+	submatches := relativeTimeRegex.FindStringSubmatch(arg)
+	if submatches == nil {
+		return time.Time{}, false
+	}
+
+	sign := 1
+	if submatches[1] == "-" {
+		sign = -1
+	}
+
+	value := now
+	for _, part := range relativeTimePartRegex.FindAllStringSubmatch(submatches[2], -1) {
+		switch part[2] {
+		case "d", "w":
+			days, err := strconv.Atoi(part[1])
+			if err != nil {
+				return time.Time{}, false
+			}
+			if part[2] == "w" {
+				days *= 7
+			}
+			value = value.AddDate(0, 0, sign*days)
+		default:
+			duration, err := time.ParseDuration(part[0])
+			if err != nil {
+				return time.Time{}, false
+			}
+			value = value.Add(time.Duration(sign) * duration)
+		}
+	}
+
+	return value, true
+
+}
+
+// SetTimeLayouts sets the layouts, in the format used by time.Parse, tried in
+// order when converting arguments to (time) captures. The default layouts
+// include RFC 3339 and the other layouts in the time package, as well as
+// dates such as 2006-01-02 and 2006-01-02 15:04.
+func (c *Commander) SetTimeLayouts(layouts ...string) {
+	c.times.layouts = layouts
+}
+
+// SetTimeLocation sets the location of (time) captures given without a time
+// zone. The default is UTC.
+func (c *Commander) SetTimeLocation(location *time.Location) {
+	c.times.location = location
+}
+
+// SetRelativeTimes sets whether (time) captures may be given relative to the
+// current time, as now, today, yesterday, tomorrow or a signed amount of time
+// such as -2h, +30m or -1w (with d for days and w for weeks). The default is
+// false.
+func (c *Commander) SetRelativeTimes(relative bool) {
+	c.times.relative = &relative
+}
+
+// TimeLayouts sets the layouts tried when converting the (time) captures of
+// the command, instead of those set for the Commander.
+//
+// See Commander.SetTimeLayouts.
+func TimeLayouts(layouts ...string) MapOption {
+	return func(cmd *command) error {
+		cmd.times.layouts = layouts
+		return nil
+	}
+}
+
+// TimeLocation sets the location of the (time) captures of the command given
+// without a time zone, instead of that set for the Commander.
+//
+// See Commander.SetTimeLocation.
+func TimeLocation(location *time.Location) MapOption {
+	return func(cmd *command) error {
+		cmd.times.location = location
+		return nil
+	}
+}
+
+// RelativeTimes sets whether the (time) captures of the command may be given
+// relative to the current time, instead of the setting for the Commander.
+//
+// See Commander.SetRelativeTimes.
+func RelativeTimes(relative bool) MapOption {
+	return func(cmd *command) error {
+		cmd.times.relative = &relative
+		return nil
+	}
+}
@@ -0,0 +1,161 @@
+package commander
+
+import (
+	"bytes"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestTimes_parse(t *testing.T) {
+
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	settings := defaultTimeSettings.with(timeSettings{now: func() time.Time { return now }})
+
+	tests := map[string]time.Time{
+		"2024-01-02":           time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		"2024-01-02 15:04":     time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+		"2024-01-02T15:04:05":  time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		"2024-01-02T15:04:05Z": time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		"3:04PM":               time.Date(2024, 3, 10, 15, 4, 0, 0, time.UTC),
+		"09:15":                time.Date(2024, 3, 10, 9, 15, 0, 0, time.UTC),
+	}
+	for arg, expected := range tests {
+		value, err := settings.parse(arg)
+		if assert.NoError(t, err, arg) {
+			assert.True(t, value.(time.Time).Equal(expected), "%s: %v", arg, value)
+		}
+	}
+
+	_, err := settings.parse("yesterday")
+	if assert.Error(t, err) {
+		assert.Equal(t, err.Error(), "not in a known time layout")
+	}
+
+	// layouts
+	layouts := settings.with(timeSettings{layouts: []string{"02/01/2006"}})
+	value, err := layouts.parse("03/02/2024")
+	if assert.NoError(t, err) {
+		assert.True(t, value.(time.Time).Equal(time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)))
+	}
+	_, err = layouts.parse("2024-01-02")
+	assert.Error(t, err)
+
+	// location
+	location := time.FixedZone("UTC+2", 2*60*60)
+	value, err = settings.with(timeSettings{location: location}).parse("2024-01-02 15:04")
+	if assert.NoError(t, err) {
+		assert.True(t, value.(time.Time).Equal(time.Date(2024, 1, 2, 13, 4, 0, 0, time.UTC)))
+	}
+
+}
+
+func TestTimes_parseRelativeTime(t *testing.T) {
+
+	now := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC)
+	today := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := map[string]time.Time{
+		"now":       now,
+		"Today":     today,
+		"yesterday": today.AddDate(0, 0, -1),
+		"tomorrow":  today.AddDate(0, 0, 1),
+		"-2h":       now.Add(-2 * time.Hour),
+		"+30m":      now.Add(30 * time.Minute),
+		"-1.5h":     now.Add(-90 * time.Minute),
+		"-3d":       now.AddDate(0, 0, -3),
+		"+1w2d":     now.AddDate(0, 0, 9),
+		"-1d12h":    now.Add(-36 * time.Hour),
+	}
+	for arg, expected := range tests {
+		value, ok := parseRelativeTime(arg, now)
+		if assert.True(t, ok, arg) {
+			assert.True(t, value.Equal(expected), "%s: %v", arg, value)
+		}
+	}
+
+	for _, arg := range []string{"2h", "-", "-2", "-1.5d", "+0.5w", "-1w1.5d", "last week", "2024-01-02"} {
+		_, ok := parseRelativeTime(arg, now)
+		assert.False(t, ok, arg)
+	}
+
+}
+
+func TestTimes_Settings(t *testing.T) {
+
+	c := New()
+	c.SetRelativeTimes(true)
+
+	var args objx.Map
+	handler := func(a objx.Map) {
+		args = a
+	}
+	c.Map("report from=(time) [to=(time)]", "", "", handler)
+	c.Map("log --since=(time)", "", "", handler, RelativeTimes(false), TimeLayouts("2006-01-02"))
+	c.Map("book at=(time)", "", "", handler, TimeLocation(time.FixedZone("UTC+2", 2*60*60)))
+
+	if assert.NoError(t, c.Run([]string{"report", "-1w", "now"})) {
+		from, to := args["from"].(time.Time), args["to"].(time.Time)
+		assert.True(t, to.Sub(from) > 6*24*time.Hour, "%v %v", from, to)
+	}
+
+	if assert.NoError(t, c.Run([]string{"log", "--since", "2024-01-02"})) {
+		assert.True(t, args["since"].(time.Time).Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)))
+	}
+	c.SetStderr(new(bytes.Buffer))
+	assert.Error(t, c.Run([]string{"log", "--since", "yesterday"}))
+	assert.Error(t, c.Run([]string{"log", "--since", "2024-01-02 15:04"}))
+
+	if assert.NoError(t, c.Run([]string{"book", "2024-01-02 15:04"})) {
+		assert.True(t, args["at"].(time.Time).Equal(time.Date(2024, 1, 2, 13, 4, 0, 0, time.UTC)))
+	}
+
+	c.SetTimeLocation(time.UTC)
+	c.SetTimeLayouts("2006-01-02")
+	assert.Error(t, c.Run([]string{"report", "Mon Jan  2 15:04:05 2006"}))
+	assert.NoError(t, c.Run([]string{"report", "2024-01-02"}))
+
+}
+
+func TestTimes_Constraint(t *testing.T) {
+
+	c := New()
+	c.SetStderr(new(bytes.Buffer))
+	c.SetTimeLayouts("02/01/2006")
+
+	// the bounds of a range of times are converted with the time settings of
+	// the command
+	assert.NoError(t, c.Map("book day=(time:01/01/2024..31/12/2024)", "", "", HandlerFunc))
+	assert.NoError(t, c.Map("log --since=(time:2024-01-01..)", "", "", HandlerFunc, TimeLayouts("2006-01-02")))
+	assert.NoError(t, c.Map("meet at=(time:2024-01-01 10:00..)", "", "", HandlerFunc,
+		TimeLayouts("2006-01-02 15:04"), TimeLocation(time.FixedZone("UTC+2", 2*60*60))))
+
+	assert.NoError(t, c.Run([]string{"book", "15/06/2024"}))
+	assert.Error(t, c.Run([]string{"book", "15/06/2025"}))
+	assert.NoError(t, c.Run([]string{"log", "--since", "2024-06-15"}))
+	assert.Error(t, c.Run([]string{"log", "--since", "2023-06-15"}))
+	for _, cmd := range c.commands {
+		if cmd.definition == "meet at=(time:2024-01-01 10:00..)" {
+			assert.True(t, cmd.arguments[1].constraint.min.(time.Time).Equal(time.Date(2024, 1, 1, 8, 0, 0, 0, time.UTC)))
+		}
+	}
+
+	err := c.Map("day at=(time:2024-01-01..)", "", "", HandlerFunc)
+	assert.Equal(t, err, &DefinitionError{"day at=(time:2024-01-01..)", 14, "the bound 2024-01-01 is not valid: not in a known time layout"})
+
+	// relative bounds are relative to the time each value is converted
+	current := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	c = New()
+	c.SetStderr(new(bytes.Buffer))
+	c.SetRelativeTimes(true)
+	c.times.now = func() time.Time {
+		return current
+	}
+	assert.NoError(t, c.Map("recent at=(time:-1d..now)", "", "", HandlerFunc, TimeLayouts("2006-01-02 15:04")))
+	assert.NoError(t, c.Run([]string{"recent", "2024-01-02 09:00"}))
+	current = current.AddDate(0, 0, 2)
+	assert.Error(t, c.Run([]string{"recent", "2024-01-02 09:00"}))
+	assert.NoError(t, c.Run([]string{"recent", "2024-01-04 09:00"}))
+
+}
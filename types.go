@@ -0,0 +1,278 @@
+package commander
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TypeParser is a func type that defines the function signature of the
+// function used to convert an argument to a value of a capture type. It
+// returns an error if the argument cannot be represented by the type.
+type TypeParser func(arg string) (interface{}, error)
+
+// captureType is a type of value that can be captured from the command line,
+// such as (int) or (time)
+type captureType struct {
+	// parse converts an argument to a value of the type
+	parse TypeParser
+
+	// description describes a value of the type, for use in help and mismatch
+	// errors, such as "a valid int"
+	description string
+}
+
+var (
+	// typeNameRegex represents the regexp for the names of capture types.
+	typeNameRegex = regexp.MustCompile(`^[^=|()\[\]:\s]+$`)
+
+	// captureTypesLock guards captureTypes
+	captureTypesLock sync.RWMutex
+
+	// captureTypes contains the registered capture types, by name
+	captureTypes = map[string]*captureType{
+		"string":   {parseString, "a string"},
+		"int":      {parseInt(0), "a valid int"},
+		"int64":    {parseInt(64), "a valid int64"},
+		"uint":     {parseUint(0), "a valid uint"},
+		"uint64":   {parseUint(64), "a valid uint64"},
+		"float":    {parseFloat, "a valid float"},
+		"float64":  {parseFloat, "a valid float64"},
+		"bool":     {parseBool, "a valid bool"},
+		timeType:   {parseTime, "a valid time"},
+		"duration": {parseDuration, "a valid duration"},
+		"url":      {parseURL, "a valid url"},
+		"ip":       {parseIP, "a valid ip"},
+		"cidr":     {parseCIDR, "a valid cidr"},
+		"file":     {parseFile, "an existing file"},
+		"dir":      {parseDir, "an existing directory"},
+		"json":     {parseJSON, "a JSON object"},
+	}
+)
+
+// RegisterType registers a capture type, so that it can be used in definitions
+// mapped afterwards. For example, after registering a "semver" type, the
+// definition "deploy version=(semver)" only matches if parse succeeds for the
+// version, and the handler is passed the value parse returned.
+//
+// The description describes a value of the type, such as "a semantic version",
+// and is shown in help and when an argument is not a valid value.
+//
+// RegisterType panics if the name is not a valid capture type name, or a type
+// with the name has already been registered.
+func RegisterType(name string, parse TypeParser, description string) {
+
+	if !typeNameRegex.MatchString(name) {
+		panic(fmt.Sprintf("%q is not a valid capture type name.", name))
+	}
+	if parse == nil {
+		panic("A parse func must be defined for each capture type registered.")
+	}
+
+	captureTypesLock.Lock()
+	defer captureTypesLock.Unlock()
+
+	if _, ok := captureTypes[name]; ok {
+		panic(fmt.Sprintf("The capture type (%s) has already been registered.", name))
+	}
+	captureTypes[name] = &captureType{parse: parse, description: description}
+
+}
+
+// lookupType gets the registered capture type with the name, or nil if there
+// is none
+func lookupType(name string) *captureType {
+
+	captureTypesLock.RLock()
+	defer captureTypesLock.RUnlock()
+
+	return captureTypes[name]
+
+}
+
+// isRegisteredType determines if a capture type with the name has been
+// registered
+func isRegisteredType(name string) bool {
+
+	return lookupType(name) != nil
+
+}
+
+// parseString leaves the arg as it is
+func parseString(arg string) (interface{}, error) {
+
+	return arg, nil
+
+}
+
+// parseInt makes a TypeParser that converts the arg to an int64 that fits in
+// bitSize bits
+func parseInt(bitSize int) TypeParser {
+
+	return func(arg string) (interface{}, error) {
+		value, err := strconv.ParseInt(arg, 10, bitSize)
+		if err != nil {
+			return nil, numberError(err, "not a whole number")
+		}
+		return value, nil
+	}
+
+}
+
+// parseUint makes a TypeParser that converts the arg to a uint64 that fits
+// in bitSize bits
+func parseUint(bitSize int) TypeParser {
+
+	return func(arg string) (interface{}, error) {
+		value, err := strconv.ParseUint(arg, 10, bitSize)
+		if err != nil {
+			return nil, numberError(err, "not a whole number of zero or more")
+		}
+		return value, nil
+	}
+
+}
+
+// parseFloat converts the arg to a float64
+func parseFloat(arg string) (interface{}, error) {
+
+	value, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return nil, numberError(err, "not a number")
+	}
+	return value, nil
+
+}
+
+// numberError describes the error returned by strconv when converting a
+// number, using syntax as the description if the arg is not a number at all
+func numberError(err error, syntax string) error {
+
+	if errors.Is(err, strconv.ErrRange) {
+		return errors.New("out of range")
+	}
+	return errors.New(syntax)
+
+}
+
+// parseBool converts the arg to a bool
+func parseBool(arg string) (interface{}, error) {
+
+	value, err := strconv.ParseBool(arg)
+	if err != nil {
+		return nil, errors.New("expected true or false")
+	}
+	return value, nil
+
+}
+
+// parseTime converts the arg to a time.Time using the default time settings
+func parseTime(arg string) (interface{}, error) {
+
+	return defaultTimeSettings.parse(arg)
+
+}
+
+// parseDuration converts the arg to a time.Duration
+func parseDuration(arg string) (interface{}, error) {
+
+	value, err := time.ParseDuration(arg)
+	if err != nil {
+		return nil, errors.New("expected a number and unit, such as 30s or 1h30m")
+	}
+	return value, nil
+
+}
+
+// parseURL converts the arg to a *url.URL, which must be absolute
+func parseURL(arg string) (interface{}, error) {
+
+	value, err := url.Parse(arg)
+	switch {
+	case err != nil:
+		return nil, errors.Unwrap(err)
+	case value.Scheme == "":
+		return nil, errors.New("missing scheme, such as https://")
+	}
+	return value, nil
+
+}
+
+// parseIP converts the arg to a net.IP
+func parseIP(arg string) (interface{}, error) {
+
+	value := net.ParseIP(arg)
+	if value == nil {
+		return nil, errors.New("expected an IPv4 or IPv6 address")
+	}
+	return value, nil
+
+}
+
+// parseCIDR converts the arg to a *net.IPNet
+func parseCIDR(arg string) (interface{}, error) {
+
+	_, value, err := net.ParseCIDR(arg)
+	if err != nil {
+		return nil, errors.New("expected an address and prefix length, such as 10.0.0.0/8")
+	}
+	return value, nil
+
+}
+
+// parseFile checks the arg is the path of an existing file, leaving it as it
+// is
+func parseFile(arg string) (interface{}, error) {
+
+	info, err := os.Stat(arg)
+	switch {
+	case os.IsNotExist(err):
+		return nil, errors.New("no such file")
+	case err != nil:
+		return nil, errors.Unwrap(err)
+	case info.IsDir():
+		return nil, errors.New("is a directory")
+	}
+	return arg, nil
+
+}
+
+// parseDir checks the arg is the path of an existing directory, leaving it as
+// it is
+func parseDir(arg string) (interface{}, error) {
+
+	info, err := os.Stat(arg)
+	switch {
+	case os.IsNotExist(err):
+		return nil, errors.New("no such directory")
+	case err != nil:
+		return nil, errors.Unwrap(err)
+	case !info.IsDir():
+		return nil, errors.New("not a directory")
+	}
+	return arg, nil
+
+}
+
+// parseJSON converts the arg to a map[string]interface{}, which must be a
+// JSON object
+func parseJSON(arg string) (interface{}, error) {
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(arg), &value); err != nil {
+		return nil, err
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected an object in { } braces")
+	}
+	return object, nil
+
+}
@@ -0,0 +1,250 @@
+package commander
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/stretchr/objx"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// castToType converts the cmdArg into a value of the given type, returning nil
+// if the cmdArg cannot be represented by that type or the type has not been
+// registered
+func castToType(cmdArg, castType string) interface{} {
+
+	value, err := convertToType(cmdArg, castType)
+	if err != nil {
+		return nil
+	}
+	return value
+
+}
+
+// semver is a version used to test custom capture types
+type semver struct {
+	major, minor, patch int
+}
+
+func parseSemver(arg string) (interface{}, error) {
+
+	parts := strings.Split(arg, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("expected major.minor.patch")
+	}
+
+	var numbers [3]int
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		numbers[i] = number
+	}
+	return semver{numbers[0], numbers[1], numbers[2]}, nil
+
+}
+
+func init() {
+	RegisterType("semver", parseSemver, "a semantic version such as 1.2.3")
+	RegisterType("environment", func(arg string) (interface{}, error) {
+		if arg != "staging" && arg != "production" {
+			return nil, fmt.Errorf("unknown environment %q", arg)
+		}
+		return arg, nil
+	}, "a deployment environment")
+	RegisterType("num", func(arg string) (interface{}, error) {
+		if i, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseFloat(arg, 64)
+	}, "a number")
+}
+
+func TestTypes_RegisterType(t *testing.T) {
+
+	assert.True(t, isRegisteredType("semver"))
+	assert.False(t, isRegisteredType("email"))
+
+	assert.Panics(t, func() {
+		RegisterType("semver", parseSemver, "")
+	})
+	assert.Panics(t, func() {
+		RegisterType("int", parseSemver, "")
+	})
+	assert.Panics(t, func() {
+		RegisterType("", parseSemver, "")
+	})
+	assert.Panics(t, func() {
+		RegisterType("sem ver", parseSemver, "")
+	})
+	assert.Panics(t, func() {
+		RegisterType("nothing", nil, "")
+	})
+
+	assert.Equal(t, castToType("1.2.3", "semver"), semver{1, 2, 3})
+	assert.Nil(t, castToType("1.2", "semver"))
+	assert.Equal(t, castValues([]string{"1.2.3", "2.0.0"}, func(arg string) (interface{}, error) {
+		return convertToType(arg, "semver")
+	}), []semver{{1, 2, 3}, {2, 0, 0}})
+
+	assert.Equal(t, describeType("semver"), "a semantic version such as 1.2.3")
+	assert.Equal(t, describeType("int"), "a valid int")
+	assert.Equal(t, describeType("string"), "a string")
+
+}
+
+func TestTypes_UnknownType(t *testing.T) {
+
+	c := New()
+
+	assert.Panics(t, func() {
+		c.MustMap("send to=(email)", "", "", func(objx.Map) {
+		})
+	})
+	assert.Panics(t, func() {
+		c.MustMap("send --to=(email)", "", "", func(objx.Map) {
+		})
+	})
+
+}
+
+func TestTypes_Matching(t *testing.T) {
+
+	c := New()
+	stdout, stderr := new(bytes.Buffer), new(bytes.Buffer)
+	c.SetStdout(stdout)
+	c.SetStderr(stderr)
+
+	var args objx.Map
+	c.Map("deploy env=(environment) version=(semver) [--rollback=(semver)]", "Deploys a version", "Deploys a version to an environment.", func(a objx.Map) {
+		args = a
+	})
+
+	if assert.NoError(t, c.Run([]string{"deploy", "staging", "1.2.3"})) {
+		assert.Equal(t, args["env"], "staging")
+		assert.Equal(t, args["version"], semver{1, 2, 3})
+	}
+
+	err := c.Run([]string{"deploy", "staging", "latest"})
+	var mismatch *MismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Error(), "argument 3 'latest' is not a semantic version such as 1.2.3 for version: expected major.minor.patch")
+	}
+
+	err = c.Run([]string{"deploy", "dev", "1.2.3"})
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Error(), "argument 2 'dev' is not a deployment environment for env: unknown environment \"dev\"")
+	}
+
+	assert.NoError(t, c.Run([]string{"help", "deploy"}))
+	assert.Contains(t, stdout.String(), "    env - a deployment environment\n")
+	assert.Contains(t, stdout.String(), "    version - a semantic version such as 1.2.3\n")
+	assert.Contains(t, stdout.String(), "    --rollback - a semantic version such as 1.2.3\n")
+
+}
+
+func TestTypes_Variable(t *testing.T) {
+
+	c := New()
+
+	var args objx.Map
+	c.Map("sum nums=(num)... [--add=(num)...]", "", "", func(a objx.Map) {
+		args = a
+	})
+
+	if assert.NoError(t, c.Run([]string{"sum", "1", "2"})) {
+		assert.Equal(t, args["nums"], []int64{1, 2})
+	}
+
+	// values of different types are collected into an []interface{}
+	if assert.NoError(t, c.Run([]string{"sum", "1", "2.5", "--add=3", "--add=0.5"})) {
+		assert.Equal(t, args["nums"], []interface{}{int64(1), 2.5})
+		assert.Equal(t, args["add"], []interface{}{int64(3), 0.5})
+	}
+
+}
+
+func TestTypes_BuiltIn(t *testing.T) {
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if !assert.NoError(t, os.WriteFile(file, nil, 0600)) {
+		return
+	}
+
+	assert.Equal(t, castToType("1.5", "float"), 1.5)
+	assert.Equal(t, castToType("-2", "float64"), float64(-2))
+	assert.Equal(t, castToType("1h30m", "duration"), 90*time.Minute)
+	assert.Equal(t, castToType("https://example.com/a", "url"), &url.URL{Scheme: "https", Host: "example.com", Path: "/a"})
+	assert.Equal(t, castToType("::1", "ip"), net.ParseIP("::1"))
+	assert.Equal(t, castToType("10.0.0.0/8", "cidr").(*net.IPNet).String(), "10.0.0.0/8")
+	assert.Equal(t, castToType(file, "file"), file)
+	assert.Equal(t, castToType(dir, "dir"), dir)
+	assert.Equal(t, castToType(`{"name":"mat","age":29}`, "json"), map[string]interface{}{"name": "mat", "age": float64(29)})
+
+	tests := []struct {
+		arg, castType, err string
+	}{
+		{"abc", "int", "not a whole number"},
+		{"99999999999999999999", "int64", "out of range"},
+		{"-1", "uint", "not a whole number of zero or more"},
+		{"1.2.3", "float", "not a number"},
+		{"yes", "bool", "expected true or false"},
+		{"soon", "time", "not in a known time layout"},
+		{"30", "duration", "expected a number and unit, such as 30s or 1h30m"},
+		{"example.com", "url", "missing scheme, such as https://"},
+		{"1.2.3", "ip", "expected an IPv4 or IPv6 address"},
+		{"10.0.0.0", "cidr", "expected an address and prefix length, such as 10.0.0.0/8"},
+		{filepath.Join(dir, "missing"), "file", "no such file"},
+		{dir, "file", "is a directory"},
+		{filepath.Join(dir, "missing"), "dir", "no such directory"},
+		{file, "dir", "not a directory"},
+		{"[1,2]", "json", "expected an object in { } braces"},
+	}
+
+	for _, test := range tests {
+		value, err := convertToType(test.arg, test.castType)
+		assert.Nil(t, value, test.castType)
+		if assert.Error(t, err, test.castType) {
+			assert.Equal(t, err.Error(), test.err, test.castType)
+		}
+	}
+
+	_, err := convertToType("{", "json")
+	assert.Error(t, err)
+
+}
+
+func TestTypes_BuiltInArguments(t *testing.T) {
+
+	c := New()
+
+	var args objx.Map
+	c.Map("wait timeout=(duration) ratio=(float) hosts=(ip)...", "", "", func(a objx.Map) {
+		args = a
+	})
+
+	if assert.NoError(t, c.Run([]string{"wait", "30s", "0.5", "10.0.0.1", "10.0.0.2"})) {
+		assert.Equal(t, args["timeout"], 30*time.Second)
+		assert.Equal(t, args["ratio"], 0.5)
+		assert.Equal(t, args["hosts"], []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")})
+	}
+
+	c.SetStderr(new(bytes.Buffer))
+	err := c.Run([]string{"wait", "30", "0.5", "10.0.0.1"})
+	var mismatch *MismatchError
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, mismatch.Error(), "argument 2 '30' is not a valid duration for timeout: expected a number and unit, such as 30s or 1h30m")
+		assert.Error(t, mismatch.Err)
+	}
+
+}